@@ -36,6 +36,9 @@ type Canvas interface {
 	Equals(onlyDirty bool, v Canvas) bool
 	Composite(v Canvas) error
 	Render(display Display) error
+	Invalidate(region Region)
+	InvalidateAll()
+	Damage() (region Region, damaged bool)
 	ForEach(fn CanvasForEachFn) EventFlag
 	DrawText(pos Point2I, size Rectangle, justify Justification, singleLineMode bool, wrap WrapMode, ellipsize bool, style Style, markup bool, text string)
 	DrawSingleLineText(position Point2I, maxChars int, ellipsize bool, justify Justification, style Style, markup bool, text string)
@@ -193,13 +196,16 @@ func (c *CCanvas) Composite(v Canvas) error {
 	return nil
 }
 
-// render this canvas upon the given display
+// render the damaged cells of this canvas upon the given display, being
+// those changed since the last render or within an invalidated region
 func (c *CCanvas) Render(display Display) error {
 	for x := 0; x < c.size.W; x++ {
 		for y := 0; y < c.size.H; y++ {
 			cell := c.buffer.Cell(x, y)
 			if cell != nil {
-				display.SetContent(x, y, cell.Value(), nil, cell.Style())
+				if c.buffer.Damaged(x, y) {
+					display.SetContent(x, y, cell.Value(), nil, cell.Style())
+				}
 			} else {
 				// display.SetContent(x, y, cell.Value(), nil, cell.Style())
 				bs := c.buffer.Size()
@@ -212,9 +218,27 @@ func (c *CCanvas) Render(display Display) error {
 			}
 		}
 	}
+	c.buffer.ClearDamage()
 	return nil
 }
 
+// mark the cells of the canvas within the given region as needing to be
+// rendered again, such as when the display has been changed by other means
+func (c *CCanvas) Invalidate(region Region) {
+	c.buffer.Invalidate(region)
+}
+
+// mark every cell of the canvas as needing to be rendered again
+func (c *CCanvas) InvalidateAll() {
+	c.buffer.InvalidateAll()
+}
+
+// return the smallest region containing every cell changed since the last
+// render or within an invalidated region
+func (c *CCanvas) Damage() (region Region, damaged bool) {
+	return c.buffer.Damage()
+}
+
 // func signature used when iterating over each cell
 type CanvasForEachFn = func(x, y int, cell TextCell) EventFlag
 
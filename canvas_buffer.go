@@ -33,15 +33,28 @@ type CanvasBuffer interface {
 	GetContent(x, y int) (textCell TextCell)
 	SetContent(x int, y int, r rune, style Style) error
 	LoadData(d [][]TextCell)
+	Damaged(x, y int) bool
+	Damage() (region Region, damaged bool)
+	Invalidate(region Region)
+	InvalidateAll()
+	ClearDamage()
 
 	sync.Locker
 }
 
+// what was last rendered of a cell, cells that are not valid are damaged
+type renderedCell struct {
+	value rune
+	style Style
+	valid bool
+}
+
 // concrete implementation of the CanvasBuffer interface
 type CCanvasBuffer struct {
-	data  [][]TextCell
-	size  Rectangle
-	style Style
+	data     [][]TextCell
+	rendered [][]renderedCell
+	size     Rectangle
+	style    Style
 
 	sync.Mutex
 }
@@ -146,8 +159,9 @@ func (b *CCanvasBuffer) Resize(size Rectangle, style Style) {
 			}
 		}
 	}
-	// store the size
+	// store the size, every cell is now damaged
 	b.size = size
+	b.rendered = nil
 }
 
 // return the text cell at the given coordinates, nil if not found
@@ -200,6 +214,102 @@ func (b *CCanvasBuffer) SetContent(x int, y int, r rune, style Style) error {
 	return fmt.Errorf("x=%v not in range [0-%d]", x, len(b.data)-1)
 }
 
+// return true if the cell at the given coordinates has changed since the
+// damage was last cleared, or is within an invalidated region
+func (b *CCanvasBuffer) Damaged(x, y int) bool {
+	b.Lock()
+	defer b.Unlock()
+	return b.damaged(x, y)
+}
+
+func (b *CCanvasBuffer) damaged(x, y int) bool {
+	if x < 0 || y < 0 || x >= b.size.W || y >= b.size.H {
+		return false
+	}
+	if x >= len(b.rendered) || y >= len(b.rendered[x]) {
+		return true
+	}
+	r := b.rendered[x][y]
+	cell := b.data[x][y]
+	return !r.valid || r.value != cell.Value() || r.style != cell.Style()
+}
+
+// return the smallest region containing all the damaged cells, damaged is
+// false if there are none
+func (b *CCanvasBuffer) Damage() (region Region, damaged bool) {
+	b.Lock()
+	defer b.Unlock()
+	minX, minY, maxX, maxY := b.size.W, b.size.H, -1, -1
+	for x := 0; x < b.size.W; x++ {
+		for y := 0; y < b.size.H; y++ {
+			if b.damaged(x, y) {
+				if x < minX {
+					minX = x
+				}
+				if y < minY {
+					minY = y
+				}
+				if x > maxX {
+					maxX = x
+				}
+				if y > maxY {
+					maxY = y
+				}
+			}
+		}
+	}
+	if maxX < 0 {
+		return MakeRegion(0, 0, 0, 0), false
+	}
+	return MakeRegion(minX, minY, maxX-minX+1, maxY-minY+1), true
+}
+
+// mark the cells within the given region as damaged, regardless of whether
+// they have changed
+func (b *CCanvasBuffer) Invalidate(region Region) {
+	b.Lock()
+	defer b.Unlock()
+	for x := region.X; x < region.X+region.W && x < len(b.rendered); x++ {
+		if x < 0 {
+			continue
+		}
+		for y := region.Y; y < region.Y+region.H && y < len(b.rendered[x]); y++ {
+			if y >= 0 {
+				b.rendered[x][y].valid = false
+			}
+		}
+	}
+}
+
+// mark every cell as damaged
+func (b *CCanvasBuffer) InvalidateAll() {
+	b.Lock()
+	defer b.Unlock()
+	b.rendered = nil
+}
+
+// record the current contents as rendered, such that no cells are damaged
+func (b *CCanvasBuffer) ClearDamage() {
+	b.Lock()
+	defer b.Unlock()
+	if len(b.rendered) != b.size.W {
+		b.rendered = make([][]renderedCell, b.size.W)
+	}
+	for x := 0; x < b.size.W; x++ {
+		if len(b.rendered[x]) != b.size.H {
+			b.rendered[x] = make([]renderedCell, b.size.H)
+		}
+		for y := 0; y < b.size.H; y++ {
+			cell := b.data[x][y]
+			b.rendered[x][y] = renderedCell{
+				value: cell.Value(),
+				style: cell.Style(),
+				valid: true,
+			}
+		}
+	}
+}
+
 // given matrix array of text cells, load that data in this canvas space
 func (b *CCanvasBuffer) LoadData(d [][]TextCell) {
 	b.Lock()
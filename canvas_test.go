@@ -0,0 +1,99 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// countingDisplay counts the cells set upon the display
+type countingDisplay struct {
+	Display
+
+	set []Point2I
+}
+
+func (c *countingDisplay) SetContent(x, y int, mainc rune, combc []rune, style Style) {
+	c.set = append(c.set, MakePoint2I(x, y))
+	c.Display.SetContent(x, y, mainc, combc, style)
+}
+
+func TestCanvasDamage(t *testing.T) {
+	Convey("Rendering only damaged cells of a canvas", t, func() {
+		o, err := MakeOffscreenDisplay("")
+		So(err, ShouldBeNil)
+		defer o.Close()
+		o.SetSize(4, 3)
+		display := &countingDisplay{Display: o}
+		canvas := NewCanvas(MakePoint2I(0, 0), MakeRectangle(4, 3), StyleDefault)
+
+		region, damaged := canvas.Damage()
+		So(damaged, ShouldBeTrue)
+		So(region, ShouldResemble, MakeRegion(0, 0, 4, 3))
+		So(canvas.Render(display), ShouldBeNil)
+		So(display.set, ShouldHaveLength, 12)
+		_, damaged = canvas.Damage()
+		So(damaged, ShouldBeFalse)
+
+		Convey("unchanged cells are not rendered again", func() {
+			display.set = nil
+			So(canvas.SetRune(0, 0, ' ', StyleDefault), ShouldBeNil)
+			So(canvas.Render(display), ShouldBeNil)
+			So(display.set, ShouldBeEmpty)
+		})
+
+		Convey("changed cells are rendered", func() {
+			display.set = nil
+			So(canvas.SetRune(1, 1, 'a', StyleDefault), ShouldBeNil)
+			So(canvas.SetRune(3, 2, 'b', StyleDefault.Bold(true)), ShouldBeNil)
+			region, damaged = canvas.Damage()
+			So(damaged, ShouldBeTrue)
+			So(region, ShouldResemble, MakeRegion(1, 1, 3, 2))
+			So(canvas.Render(display), ShouldBeNil)
+			So(display.set, ShouldResemble, []Point2I{{1, 1}, {3, 2}})
+			o.Show()
+			cells, w, _ := o.GetContents()
+			So(cells[(1*w)+1].Runes, ShouldResemble, []rune{'a'})
+			// changed through the cell itself
+			display.set = nil
+			canvas.GetContent(2, 0).Set('c')
+			So(canvas.Render(display), ShouldBeNil)
+			So(display.set, ShouldResemble, []Point2I{{2, 0}})
+		})
+
+		Convey("invalidated regions are rendered", func() {
+			display.set = nil
+			canvas.Invalidate(MakeRegion(2, 1, 5, 5))
+			region, damaged = canvas.Damage()
+			So(damaged, ShouldBeTrue)
+			So(region, ShouldResemble, MakeRegion(2, 1, 2, 2))
+			So(canvas.Render(display), ShouldBeNil)
+			So(display.set, ShouldHaveLength, 4)
+			display.set = nil
+			canvas.InvalidateAll()
+			So(canvas.Render(display), ShouldBeNil)
+			So(display.set, ShouldHaveLength, 12)
+		})
+
+		Convey("resizing damages every cell", func() {
+			display.set = nil
+			canvas.Resize(MakeRectangle(2, 2), StyleDefault.Reverse(true))
+			So(canvas.Render(display), ShouldBeNil)
+			So(display.set, ShouldHaveLength, 4)
+		})
+	})
+}
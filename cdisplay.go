@@ -51,9 +51,29 @@ var (
 // $COLUMNS environment variables can be set to the actual window size,
 // otherwise defaults taken from the terminal database are used.
 func NewTerminfoDisplay() (Display, error) {
-	ti, e := terminfo.LookupTerminfo(os.Getenv("TERM"))
+	return NewTerminfoDisplayWithTtyPath("")
+}
+
+// NewTerminfoDisplayWithTtyPath is the same as NewTerminfoDisplay except that
+// the display is driven through the tty (or pty) device found at the given
+// ttyPath instead of the process stdin and stdout.  This leaves stdout and
+// stderr free for other uses, such as logging.  An empty ttyPath is the same
+// as calling NewTerminfoDisplay.
+func NewTerminfoDisplayWithTtyPath(ttyPath string) (Display, error) {
+	t, err := newTerminfoDisplay(os.Getenv("TERM"))
+	if err != nil {
+		return nil, err
+	}
+	t.ttyPath = ttyPath
+	return t, nil
+}
+
+// newTerminfoDisplay prepares a cDisplay for the named terminal type, leaving
+// the choice of input and output to the caller.
+func newTerminfoDisplay(termName string) (*cDisplay, error) {
+	ti, e := terminfo.LookupTerminfo(termName)
 	if e != nil {
-		ti, e = loadDynamicTerminfo(os.Getenv("TERM"))
+		ti, e = loadDynamicTerminfo(termName)
 		if e != nil {
 			return nil, e
 		}
@@ -69,6 +89,9 @@ func NewTerminfoDisplay() (Display, error) {
 	t.prepareKeys()
 	t.buildAcsMap()
 	t.sigwinch = make(chan os.Signal, SignalQueueSize)
+	t.sigtstp = make(chan os.Signal, SignalQueueSize)
+	t.sigcont = make(chan os.Signal, SignalQueueSize)
+	t.resizeQ = make(chan struct{}, 1)
 	t.fallback = make(map[rune]string)
 	for k, v := range RuneFallbacks {
 		t.fallback[k] = v
@@ -86,20 +109,34 @@ type tKeyCode struct {
 // cDisplay represents a display backed by a terminfo implementation.
 type cDisplay struct {
 	ti           *terminfo.Terminfo
+	ttyPath      string
+	tty          *os.File
+	stream       io.ReadWriter
+	streamW      int
+	streamH      int
 	h            int
 	w            int
 	finished     bool
 	cells        *CellBuffer
-	in           *os.File
-	out          *os.File
+	in           io.Reader
+	out          io.Writer
 	buffering    bool // true if we are collecting writes to buf instead of sending directly to out
 	buf          bytes.Buffer
 	curStyle     Style
 	style        Style
 	evCh         chan Event
 	sigwinch     chan os.Signal
+	sigtstp      chan os.Signal
+	sigcont      chan os.Signal
+	resizeQ      chan struct{}
+	inline       bool
+	inlineHeight int
+	inlineRows   int
+	inlineY      int
+	inlineUsed   int
 	quit         chan struct{}
 	inDoneQ      chan struct{}
+	inputDoneQ   chan struct{}
 	keyExist     map[Key]bool
 	keyCodes     map[string]*tKeyCode
 	keyChan      chan []byte
@@ -108,9 +145,27 @@ type cDisplay struct {
 	cx           int
 	cy           int
 	mouse        []byte
+	mouseFlags   MouseFlags
+	lastMouse    *EventMouse
 	clear        bool
 	cursorX      int
 	cursorY      int
+	cursorStyle  CursorStyle
+	cursorColor  Color
+	sentStyle    CursorStyle
+	sentColor    Color
+	ss           string
+	se           string
+	hyperlinks   bool
+	title        string
+	titlePushed  bool
+	tsl          string
+	fsl          string
+	kittyFlags   KittyKeyboardFlags
+	kittyQueried bool
+	kittyActive  bool
+	syncMode     SyncOutputMode
+	syncCapable  bool
 	wasBtn       bool
 	acs          map[rune]string
 	charset      string
@@ -125,7 +180,13 @@ type cDisplay struct {
 	finishOnce   sync.Once
 	enablePaste  string
 	disablePaste string
+	enableFocus  string
+	disableFocus string
+	pasteOn      bool
+	focusOn      bool
+	suspended    bool
 	saved        *term.State
+	inLock       sync.Mutex
 
 	sync.Mutex
 }
@@ -133,11 +194,14 @@ type cDisplay struct {
 func (t *cDisplay) Init() error {
 	t.evCh = make(chan Event, EventQueueSize)
 	t.inDoneQ = make(chan struct{})
+	t.inputDoneQ = make(chan struct{})
 	t.keyChan = make(chan []byte, EventKeyQueueSize)
 	t.keyTimer = time.NewTimer(EventKeyTiming)
 	t.cells = NewCellBuffer()
 
-	t.charset = GetCharset()
+	if t.charset == "" {
+		t.charset = GetCharset()
+	}
 	if enc := GetEncoding(t.charset); enc != nil {
 		t.encoder = enc.NewEncoder()
 		t.decoder = enc.NewDecoder()
@@ -155,13 +219,24 @@ func (t *cDisplay) Init() error {
 	if i, _ := strconv.Atoi(os.Getenv("COLUMNS")); i != 0 {
 		w = i
 	}
-	if e := t.initialize(); e != nil {
+	if t.stream != nil {
+		if e := t.initializeStream(); e != nil {
+			return e
+		}
+	} else if e := t.initialize(); e != nil {
 		return e
 	}
 
 	if t.ti.SetFgBgRGB != "" || t.ti.SetFgRGB != "" || t.ti.SetBgRGB != "" {
 		t.trueColor = true
 	}
+	extras := lookupTerminfoExtras(t.ti.Name)
+	t.ss, t.se = extras.setCursorStyle, extras.resetCursorStyle
+	if extras.toStatusLine != "" && extras.fromStatusLine != "" {
+		t.tsl, t.fsl = extras.toStatusLine, extras.fromStatusLine
+	}
+	t.syncCapable = extras.syncOutput
+	t.hyperlinks = t.xtermLike()
 	// A user who wants to have his themes honored can
 	// set this environment variable.
 	if os.Getenv("GO_CDK_TRUECOLOR") == "disable" {
@@ -175,10 +250,17 @@ func (t *cDisplay) Init() error {
 		t.colors[Color(i)|ColorValid] = Color(i) | ColorValid
 	}
 
-	t.TPuts(ti.EnterCA)
-	t.TPuts(ti.HideCursor)
-	t.TPuts(ti.EnableAcs)
-	t.TPuts(ti.Clear)
+	if t.inline {
+		t.TPuts(ti.HideCursor)
+		t.TPuts(ti.EnableAcs)
+		t.initInline()
+	} else {
+		t.TPuts(ti.EnterCA)
+		t.TPuts(ti.HideCursor)
+		t.TPuts(ti.EnableAcs)
+		t.TPuts(ti.Clear)
+	}
+	t.querySyncOutput()
 
 	t.quit = make(chan struct{})
 
@@ -189,11 +271,18 @@ func (t *cDisplay) Init() error {
 	t.cells.Resize(w, h)
 	t.cursorX = -1
 	t.cursorY = -1
+	t.cursorStyle, t.sentStyle = CursorStyleDefault, CursorStyleDefault
+	t.cursorColor, t.sentColor = ColorDefault, ColorDefault
 	t.resize()
 	t.Unlock()
 
+	fd := -1
+	if t.stream == nil {
+		// waited upon, so that the input can be held, see holdInput
+		fd = int(t.tty.Fd())
+	}
 	go t.mainLoop()
-	go t.inputLoop()
+	go t.inputLoop(fd)
 
 	return nil
 }
@@ -309,6 +398,24 @@ func (t *cDisplay) prepareBracketedPaste() {
 	}
 }
 
+// xtermLike returns true if the terminal is taken to be like xterm, those
+// reporting the mouse as xterm does.  terminfo has no capabilities for what
+// xterm has added since, such as focus reporting, the title and cursor color,
+// hyperlinks or the kitty keyboard query, which are only sent to these.
+func (t *cDisplay) xtermLike() bool {
+	return t.ti.Mouse != ""
+}
+
+func (t *cDisplay) prepareFocusReporting() {
+	// like xterm, DECSET 1004
+	if t.xtermLike() {
+		t.enableFocus = "\x1b[?1004h"
+		t.disableFocus = "\x1b[?1004l"
+		t.prepareKey(keyFocusIn, "\x1b[I")
+		t.prepareKey(keyFocusOut, "\x1b[O")
+	}
+}
+
 func (t *cDisplay) prepareKey(key Key, val string) {
 	t.prepareKeyMod(key, ModNone, val)
 }
@@ -448,6 +555,7 @@ func (t *cDisplay) prepareKeys() {
 	t.prepareKey(keyPasteEnd, ti.PasteEnd)
 	t.prepareXtermModifiers()
 	t.prepareBracketedPaste()
+	t.prepareFocusReporting()
 
 outer:
 	// Add key mappings for control keys.
@@ -485,12 +593,22 @@ func (t *cDisplay) finish() {
 
 	ti := t.ti
 	t.cells.Resize(0, 0)
-	t.TPuts(ti.ShowCursor)
-	t.TPuts(ti.AttrOff)
-	t.TPuts(ti.Clear)
-	t.TPuts(ti.ExitCA)
+	t.endHyperlink()
+	t.restoreTitle()
+	t.cursorStyle, t.cursorColor = CursorStyleDefault, ColorDefault
+	t.sendCursorStyle()
+	if t.inline {
+		t.inlineFinish()
+	} else {
+		t.TPuts(ti.ShowCursor)
+		t.TPuts(ti.AttrOff)
+		t.TPuts(ti.Clear)
+		t.TPuts(ti.ExitCA)
+	}
 	t.TPuts(ti.ExitKeypad)
 	t.TPuts(t.disablePaste)
+	t.TPuts(t.disableFocus)
+	t.disableKittyKeyboard()
 	t.DisableMouse()
 	t.curStyle = styleInvalid
 	t.clear = false
@@ -504,7 +622,11 @@ func (t *cDisplay) finish() {
 		close(t.quit)
 	}
 
-	t.finalize()
+	if t.stream != nil {
+		t.finalizeStream()
+	} else {
+		t.finalize()
+	}
 }
 
 func (t *cDisplay) SetStyle(style Style) {
@@ -654,7 +776,7 @@ func (t *cDisplay) drawCell(x, y int) int {
 	}
 
 	if t.cy != y || t.cx != x {
-		t.TPuts(ti.TGoto(x, y))
+		t.goTo(x, y)
 		t.cx = x
 		t.cy = y
 	}
@@ -665,6 +787,8 @@ func (t *cDisplay) drawCell(x, y int) int {
 	if style != t.curStyle {
 		fg, bg, attrs := style.Decompose()
 
+		t.sendHyperlink(style)
+
 		t.TPuts(ti.AttrOff)
 
 		t.sendFgBg(fg, bg)
@@ -741,15 +865,64 @@ func (t *cDisplay) HideCursor() {
 	t.ShowCursor(-1, -1)
 }
 
+// GetCursor returns the cursor position and whether it is within the bounds
+// of the display, and therefore visible.
+func (t *cDisplay) GetCursor() (x int, y int, visible bool) {
+	t.Lock()
+	defer t.Unlock()
+	x, y = t.cursorX, t.cursorY
+	w, h := t.cells.Size()
+	visible = x >= 0 && y >= 0 && x < w && y < h
+	return
+}
+
+func (t *cDisplay) SetCursorStyle(style CursorStyle) {
+	if !style.IsValid() {
+		style = CursorStyleDefault
+	}
+	t.Lock()
+	t.cursorStyle = style
+	t.Unlock()
+}
+
+func (t *cDisplay) SetCursorColor(color Color) {
+	if color.Hex() < 0 {
+		color = ColorDefault
+	}
+	t.Lock()
+	t.cursorColor = color
+	t.Unlock()
+}
+
+// sendCursorStyle sends the cursor style and color to the terminal, if
+// changed since last sent
+func (t *cDisplay) sendCursorStyle() {
+	if t.cursorStyle != t.sentStyle && t.ss != "" {
+		if t.cursorStyle == CursorStyleDefault && t.se != "" {
+			t.TPuts(t.se)
+		} else {
+			t.TPuts(t.ti.TParm(t.ss, int(t.cursorStyle)))
+		}
+		t.sentStyle = t.cursorStyle
+	}
+	if t.cursorColor != t.sentColor && t.xtermLike() {
+		t.writeString(cursorColorSequence(t.cursorColor))
+		t.sentColor = t.cursorColor
+	}
+}
+
 func (t *cDisplay) showCursor() {
 
 	x, y := t.cursorX, t.cursorY
 	w, h := t.cells.Size()
+	if t.inline {
+		h = t.inlineUsed
+	}
 	if x < 0 || y < 0 || x >= w || y >= h {
 		t.hideCursor()
 		return
 	}
-	t.TPuts(t.ti.TGoto(x, y))
+	t.goTo(x, y)
 	t.TPuts(t.ti.ShowCursor)
 	t.cx = x
 	t.cy = y
@@ -779,7 +952,7 @@ func (t *cDisplay) TPuts(s string) {
 
 func (t *cDisplay) Show() {
 	t.Lock()
-	if !t.finished {
+	if !t.finished && !t.suspended {
 		t.resize()
 		t.draw()
 	}
@@ -787,6 +960,10 @@ func (t *cDisplay) Show() {
 }
 
 func (t *cDisplay) clearDisplay() {
+	if t.inline {
+		t.inlineClear()
+		return
+	}
 	fg, bg, _ := t.style.Decompose()
 	t.sendFgBg(fg, bg)
 	t.TPuts(t.ti.Clear)
@@ -801,8 +978,17 @@ func (t *cDisplay) hideCursor() {
 		// No way to hide cursor, stick it
 		// at bottom right of display
 		t.cx, t.cy = t.cells.Size()
-		t.TPuts(t.ti.TGoto(t.cx, t.cy))
+		t.goTo(t.cx, t.cy)
+	}
+}
+
+// goTo moves the cursor to the given position on the display
+func (t *cDisplay) goTo(x, y int) {
+	if t.inline {
+		t.inlineGoTo(x, y)
+		return
 	}
+	t.TPuts(t.ti.TGoto(x, y))
 }
 
 func (t *cDisplay) draw() {
@@ -815,6 +1001,10 @@ func (t *cDisplay) draw() {
 	defer func() {
 		t.buffering = false
 	}()
+	synced := t.syncOutput()
+	if synced {
+		t.writeString(syncOutputBegin)
+	}
 
 	// hide the cursor while we move stuff around
 	t.hideCursor()
@@ -823,7 +1013,11 @@ func (t *cDisplay) draw() {
 		t.clearDisplay()
 	}
 
-	for y := 0; y < t.h; y++ {
+	rows := t.h
+	if t.inline {
+		rows = t.inlinePrepare()
+	}
+	for y := 0; y < rows; y++ {
 		for x := 0; x < t.w; x++ {
 			width := t.drawCell(x, y)
 			if width > 1 {
@@ -837,14 +1031,23 @@ func (t *cDisplay) draw() {
 			x += width - 1
 		}
 	}
+	t.endHyperlink()
 
 	// restore the cursor
+	t.sendCursorStyle()
 	t.showCursor()
 
+	if synced {
+		t.writeString(syncOutputEnd)
+	}
 	_, _ = t.buf.WriteTo(t.out)
 }
 
 func (t *cDisplay) EnableMouse(flags ...MouseFlags) {
+	if t.inline {
+		// mouse positions are relative to the screen, not the region
+		return
+	}
 	var f MouseFlags
 	flagsPresent := false
 	for _, flag := range flags {
@@ -872,11 +1075,17 @@ func (t *cDisplay) EnableMouse(flags ...MouseFlags) {
 			return
 		}
 
+		t.mouseFlags = f
 		t.TPuts(fmt.Sprintf("\x1b[?%dh\x1b[?1006h", mm))
 	}
 }
 
 func (t *cDisplay) DisableMouse() {
+	t.mouseFlags = 0
+	t.disableMouse()
+}
+
+func (t *cDisplay) disableMouse() {
 	if len(t.mouse) != 0 {
 		// This turns off everything.
 		t.TPuts("\x1b[?1000l\x1b[?1002l\x1b[?1003l\x1b[?1006l")
@@ -884,13 +1093,25 @@ func (t *cDisplay) DisableMouse() {
 }
 
 func (t *cDisplay) EnablePaste() {
+	t.pasteOn = true
 	t.TPuts(t.enablePaste)
 }
 
 func (t *cDisplay) DisablePaste() {
+	t.pasteOn = false
 	t.TPuts(t.disablePaste)
 }
 
+func (t *cDisplay) EnableFocus() {
+	t.focusOn = true
+	t.TPuts(t.enableFocus)
+}
+
+func (t *cDisplay) DisableFocus() {
+	t.focusOn = false
+	t.TPuts(t.disableFocus)
+}
+
 func (t *cDisplay) Size() (w, h int) {
 	t.Lock()
 	w, h = t.w, t.h
@@ -899,13 +1120,17 @@ func (t *cDisplay) Size() (w, h int) {
 }
 
 func (t *cDisplay) resize() {
-	if w, h, e := t.getWinSize(); e == nil {
+	if w, h, e := t.windowSize(); e == nil {
 		if w != t.w || h != t.h {
 			t.cx = -1
 			t.cy = -1
 
 			t.cells.Resize(w, h)
 			t.cells.Invalidate()
+			if t.inline && t.w != 0 {
+				// the terminal may have reflowed the region, so start over
+				t.clear = true
+			}
 			t.h = h
 			t.w = w
 			ev := NewEventResize(w, h)
@@ -1091,7 +1316,8 @@ func (t *cDisplay) buildMouseEvent(x, y, btn int) *EventMouse {
 	// to the display in that case.
 	x, y = t.clip(x, y)
 
-	return NewEventMouse(x, y, button, mod)
+	t.lastMouse = newEventMouse(t.lastMouse, x, y, button, mod)
+	return t.lastMouse
 }
 
 // parseSgrMouse attempts to locate an SGR mouse record at the start of the
@@ -1291,6 +1517,10 @@ func (t *cDisplay) parseFunctionKey(buf *bytes.Buffer, evs *[]Event) (bool, bool
 				*evs = append(*evs, NewEventPaste(true))
 			case keyPasteEnd:
 				*evs = append(*evs, NewEventPaste(false))
+			case keyFocusIn:
+				*evs = append(*evs, NewEventFocus(true))
+			case keyFocusOut:
+				*evs = append(*evs, NewEventFocus(false))
 			default:
 				*evs = append(*evs, NewEventKey(k.key, r, mod))
 			}
@@ -1386,12 +1616,36 @@ func (t *cDisplay) collectEventsFromInput(buf *bytes.Buffer, expire bool) []Even
 			partials++
 		}
 
+		if part, comp := t.parseKittyKey(buf, &res); comp {
+			continue
+		} else if part {
+			partials++
+		}
+
+		if part, comp := t.parseKittyReply(buf); comp {
+			continue
+		} else if part {
+			partials++
+		}
+
 		if part, comp := t.parseFunctionKey(buf, &res); comp {
 			continue
 		} else if part {
 			partials++
 		}
 
+		if part, comp := t.parseModeReport(buf); comp {
+			continue
+		} else if part {
+			partials++
+		}
+
+		if part, comp := t.parseClipboardReport(buf, &res); comp {
+			continue
+		} else if part {
+			partials++
+		}
+
 		// Only parse mouse records if this term claims to have
 		// mouse support
 
@@ -1450,13 +1704,16 @@ func (t *cDisplay) mainLoop() {
 			close(t.inDoneQ)
 			return
 		case <-t.sigwinch:
-			t.Lock()
-			t.cx = -1
-			t.cy = -1
-			t.resize()
-			t.cells.Invalidate()
-			t.draw()
-			t.Unlock()
+			t.redrawResized()
+			continue
+		case <-t.resizeQ:
+			t.redrawResized()
+			continue
+		case <-t.sigtstp:
+			_ = t.PostEvent(NewEventSuspend(true))
+			continue
+		case <-t.sigcont:
+			_ = t.PostEvent(NewEventSuspend(false))
 			continue
 		case <-t.keyTimer.C:
 			// If the timer fired, and the current time
@@ -1495,27 +1752,104 @@ func (t *cDisplay) mainLoop() {
 	}
 }
 
-func (t *cDisplay) inputLoop() {
+// redrawResized picks up a change in the window size and redraws the entire
+// display to match.
+func (t *cDisplay) redrawResized() {
+	t.Lock()
+	if t.suspended {
+		// drawn when resumed
+		t.Unlock()
+		return
+	}
+	t.cx = -1
+	t.cy = -1
+	t.resize()
+	t.cells.Invalidate()
+	t.draw()
+	t.Unlock()
+}
+
+// windowSize returns the current dimensions of whatever the display is
+// rendering to.
+func (t *cDisplay) windowSize() (int, int, error) {
+	if t.stream != nil {
+		return t.getStreamSize()
+	}
+	w, h, err := t.getWinSize()
+	if err == nil && t.inline {
+		w, h = t.inlineSize(w, h)
+	}
+	return w, h, err
+}
 
+// inputLoop reads the input of the display until it quits, waiting upon
+// the tty fd given, or reading the stream when the fd is -1.  The inputDoneQ
+// is closed once the input is no longer read.
+func (t *cDisplay) inputLoop(fd int) {
+	defer close(t.inputDoneQ)
 	for {
 		chunk := make([]byte, 128)
-		n, e := t.in.Read(chunk)
+		n, e := t.readInput(fd, chunk)
+		if n == 0 && e == nil {
+			select {
+			case <-t.quit:
+				return
+			default:
+				continue
+			}
+		}
 		switch e {
 		case io.EOF:
+			// a stream reaching EOF has been disconnected and will
+			// never produce more input
+			if t.stream != nil {
+				if n > 0 {
+					t.sendInput(chunk[:n])
+				}
+				_ = t.PostEvent(NewEventError(e))
+				return
+			}
 		case nil:
 		default:
 			_ = t.PostEvent(NewEventError(e))
 			return
 		}
-		t.keyChan <- chunk[:n]
+		if !t.sendInput(chunk[:n]) {
+			return
+		}
 	}
 }
 
+// sendInput hands a chunk of input to the mainLoop, returning false if the
+// display quit instead
+func (t *cDisplay) sendInput(chunk []byte) bool {
+	select {
+	case t.keyChan <- chunk:
+		return true
+	case <-t.quit:
+		return false
+	}
+}
+
+// readInput reads a chunk of input, waiting no longer than
+// TtyInputPollInterval for the tty to have some so that holdInput is never
+// kept waiting long.  Returns no input and no error if there was none.
+func (t *cDisplay) readInput(fd int, chunk []byte) (int, error) {
+	t.inLock.Lock()
+	defer t.inLock.Unlock()
+	if fd >= 0 {
+		if ready, err := t.waitInput(fd); !ready || err != nil {
+			return 0, err
+		}
+	}
+	return t.in.Read(chunk)
+}
+
 func (t *cDisplay) Sync() {
 	t.Lock()
 	t.cx = -1
 	t.cy = -1
-	if !t.finished {
+	if !t.finished && !t.suspended {
 		t.resize()
 		t.clear = true
 		t.cells.Invalidate()
@@ -0,0 +1,113 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+)
+
+// SetClipboard sets the given selection to the text given using OSC 52, which
+// works over remote connections as it is the terminal that sets the
+// clipboard.  Terminals that do not support OSC 52, or have it disabled,
+// silently ignore it.
+func (t *cDisplay) SetClipboard(selection ClipboardSelection, text string) error {
+	if err := checkClipboard(selection, text); err != nil {
+		return err
+	}
+	t.Lock()
+	defer t.Unlock()
+	if t.finished {
+		return fmt.Errorf("display is closed")
+	}
+	t.writeString(osc52Sequence(selection, text))
+	return nil
+}
+
+// RequestClipboard asks the terminal for the contents of the given selection
+// using OSC 52, the reply is posted as an EventClipboard.  Many terminals do
+// not permit reading the clipboard, in which case there is no reply.
+func (t *cDisplay) RequestClipboard(selection ClipboardSelection) error {
+	if !selection.IsValid() {
+		return fmt.Errorf("invalid clipboard selection: %q", string(selection))
+	}
+	t.Lock()
+	defer t.Unlock()
+	if t.finished {
+		return fmt.Errorf("display is closed")
+	}
+	t.writeString(osc52RequestSequence(selection))
+	return nil
+}
+
+// parseClipboardReport parses the reply to an OSC 52 request, of the form:
+// OSC 52 ; selection ; base64 text, terminated with either BEL or ST
+func (t *cDisplay) parseClipboardReport(buf *bytes.Buffer, evs *[]Event) (bool, bool) {
+	b := buf.Bytes()
+	prefix := []byte("\x1b]52;")
+	if len(b) < len(prefix) {
+		return bytes.HasPrefix(prefix, b), false
+	}
+	if !bytes.HasPrefix(b, prefix) {
+		return false, false
+	}
+	selection := SelectionClipboard
+	i := len(prefix)
+	for start := i; i < len(b) && b[i] != ';'; i++ {
+		if b[i] < 'a' || b[i] > 'z' {
+			return false, false
+		}
+		if i == start {
+			selection = ClipboardSelection(b[i : i+1])
+		}
+	}
+	if i >= len(b) {
+		return true, false
+	}
+	data := i + 1
+	for i = data; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c == '\x07':
+			t.clipboardReport(selection, b[data:i], evs)
+			buf.Next(i + 1)
+			return true, true
+		case c == '\x1b':
+			if i+1 >= len(b) {
+				return true, false
+			}
+			if b[i+1] != '\\' {
+				return false, false
+			}
+			t.clipboardReport(selection, b[data:i], evs)
+			buf.Next(i + 2)
+			return true, true
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '+', c == '/', c == '=':
+		default:
+			return false, false
+		}
+	}
+	return true, false
+}
+
+func (t *cDisplay) clipboardReport(selection ClipboardSelection, data []byte, evs *[]Event) {
+	text, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		*evs = append(*evs, NewEventError(fmt.Errorf("invalid clipboard report: %v", err)))
+		return
+	}
+	*evs = append(*evs, NewEventClipboard(selection, string(text)))
+}
@@ -0,0 +1,40 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"os"
+)
+
+// externalTty returns the tty the display is on, for running other programs
+// upon, or nil for displays on a stream.
+func (t *cDisplay) externalTty() *os.File {
+	if t.stream != nil {
+		return nil
+	}
+	return t.tty
+}
+
+// holdInput stops the display reading input from the tty, once any read under
+// way is done, so that another program can read the tty instead.  Input is
+// read again once releaseInput is called.
+func (t *cDisplay) holdInput() {
+	t.inLock.Lock()
+}
+
+// releaseInput undoes holdInput.
+func (t *cDisplay) releaseInput() {
+	t.inLock.Unlock()
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// end the hyperlink in effect, OSC 8 with an empty target
+const hyperlinkClose = "\x1b]8;;\x1b\\"
+
+// hyperlinkSequence returns the escape sequence, OSC 8, starting a hyperlink
+// to the url given, or ending the hyperlink in effect for an empty url.  The
+// url and id may only hold printable ASCII, others are percent encoded and
+// the separators of the sequence are left out of the id.
+func hyperlinkSequence(url, id string) string {
+	if url == "" {
+		return hyperlinkClose
+	}
+	params := ""
+	if id != "" {
+		params = "id=" + strings.Map(func(r rune) rune {
+			if r <= ' ' || r > '~' || r == ':' || r == ';' || r == '=' {
+				return -1
+			}
+			return r
+		}, id)
+	}
+	var sb strings.Builder
+	for _, b := range []byte(url) {
+		if b < ' ' || b > '~' {
+			_, _ = fmt.Fprintf(&sb, "%%%02X", b)
+			continue
+		}
+		sb.WriteByte(b)
+	}
+	return "\x1b]8;" + params + ";" + sb.String() + "\x1b\\"
+}
+
+// sendHyperlink starts, changes or ends the hyperlink in effect for the
+// style given, which is about to become the current style
+func (t *cDisplay) sendHyperlink(style Style) {
+	if !t.hyperlinks {
+		return
+	}
+	url, id := style.Hyperlink()
+	curUrl, curId := t.curStyle.Hyperlink()
+	if url == curUrl && id == curId {
+		return
+	}
+	t.writeString(hyperlinkSequence(url, id))
+}
+
+// endHyperlink ends the hyperlink in effect, if any, such that nothing
+// written outside of drawing cells becomes part of it
+func (t *cDisplay) endHyperlink() {
+	if url, _ := t.curStyle.Hyperlink(); url != "" && t.hyperlinks {
+		t.writeString(hyperlinkClose)
+		t.curStyle = styleInvalid
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// InlineContentHeight can be given as the height of an inline display to
+// have the region grow and shrink with the rows of content drawn, up to the
+// height of the terminal.
+const InlineContentHeight = 0
+
+// NewInlineDisplay returns a Display that renders to a region of the given
+// height, starting at the line the cursor is on, instead of switching to the
+// alternate screen and taking over the whole terminal.  When closed, the
+// region is left as-is in the scrollback and the cursor is placed at the
+// start of the line following it.  Only relative cursor movements are used,
+// so the region does not need to know where on the screen it is.
+//
+// Because the position of the region on the screen is not known, inline
+// displays do not enable mouse reporting.
+//
+// The height is clamped to the height of the terminal, a height of
+// InlineContentHeight sizes the region to fit the content drawn.  As with
+// NewTerminfoDisplayWithTtyPath, an empty ttyPath uses stdin and stdout.
+func NewInlineDisplay(ttyPath string, height int) (Display, error) {
+	t, err := newTerminfoDisplay(os.Getenv("TERM"))
+	if err != nil {
+		return nil, err
+	}
+	t.ttyPath = ttyPath
+	t.inline = true
+	t.inlineHeight = height
+	return t, nil
+}
+
+// initInline claims the line the cursor is on as the first row of the region
+func (t *cDisplay) initInline() {
+	t.writeString("\r")
+	t.inlineY = 0
+	t.inlineRows = 1
+	t.inlineUsed = 0
+}
+
+// inlineSize returns the size of the region for the given terminal size
+func (t *cDisplay) inlineSize(w, h int) (int, int) {
+	if t.inlineHeight > InlineContentHeight && t.inlineHeight < h {
+		h = t.inlineHeight
+	}
+	if h > 0 {
+		// the terminal may have shrunk beneath the region, the rows that
+		// no longer fit have scrolled off the top
+		if t.inlineRows > h {
+			t.inlineRows = h
+		}
+		if t.inlineY >= h {
+			t.inlineY = h - 1
+		}
+	}
+	return w, h
+}
+
+// inlineGoTo moves the cursor to the given position within the region using
+// relative movements.  The row the cursor is on is always known, the column
+// is set from the start of the line.
+func (t *cDisplay) inlineGoTo(x, y int) {
+	if y >= t.inlineRows {
+		y = t.inlineRows - 1
+	}
+	if y < 0 {
+		y = 0
+	}
+	if y < t.inlineY {
+		t.writeString(fmt.Sprintf("\x1b[%dA", t.inlineY-y))
+	} else if y > t.inlineY {
+		t.writeString(fmt.Sprintf("\x1b[%dB", y-t.inlineY))
+	}
+	t.inlineY = y
+	t.writeString("\r")
+	if x > 0 {
+		t.writeString(fmt.Sprintf("\x1b[%dC", x))
+	}
+}
+
+// inlineRowsNeeded returns the number of rows of the region to draw, which
+// is the whole region unless sized to fit the content
+func (t *cDisplay) inlineRowsNeeded() int {
+	if t.inlineHeight > InlineContentHeight {
+		return t.h
+	}
+	rows := 1
+	if t.cursorY >= 0 && t.cursorY < t.h && t.cursorX >= 0 && t.cursorX < t.w {
+		rows = t.cursorY + 1
+	}
+	for y := t.h - 1; y >= rows; y-- {
+		for x := 0; x < t.w; x++ {
+			if mc, _, _, _ := t.cells.GetContent(x, y); mc != ' ' && mc != 0 {
+				return y + 1
+			}
+		}
+	}
+	return rows
+}
+
+// inlinePrepare makes sure the terminal has enough lines for the rows about
+// to be drawn, scrolling the terminal if need be, and erases any rows no
+// longer needed.  Returns the number of rows to draw.
+func (t *cDisplay) inlinePrepare() int {
+	rows := t.inlineRowsNeeded()
+	if rows > t.inlineRows {
+		// line feeds scroll the terminal once at the bottom
+		t.inlineGoTo(0, t.inlineRows-1)
+		t.writeString(strings.Repeat("\n", rows-t.inlineRows))
+		t.inlineRows = rows
+		t.inlineY = rows - 1
+		t.cx, t.cy = -1, -1
+	}
+	if rows < t.inlineUsed {
+		t.inlineGoTo(0, rows)
+		t.writeString("\x1b[J")
+		t.cx, t.cy = -1, -1
+		for y := rows; y < t.h; y++ {
+			for x := 0; x < t.w; x++ {
+				t.cells.SetDirty(x, y, true)
+			}
+		}
+	}
+	t.inlineUsed = rows
+	return rows
+}
+
+// inlineClear erases the region, leaving the lines reserved for it in place
+func (t *cDisplay) inlineClear() {
+	fg, bg, _ := t.style.Decompose()
+	t.sendFgBg(fg, bg)
+	t.inlineGoTo(0, 0)
+	t.writeString("\x1b[J")
+	t.cx, t.cy = -1, -1
+	t.inlineUsed = 0
+	t.clear = false
+}
+
+// inlineFinish leaves the region in the scrollback, placing the cursor at the
+// start of the line following the last row drawn
+func (t *cDisplay) inlineFinish() {
+	last := t.inlineUsed - 1
+	if last < 0 {
+		last = 0
+	}
+	t.TPuts(t.ti.AttrOff)
+	t.inlineGoTo(0, last)
+	t.writeString("\n\r")
+	t.TPuts(t.ti.ShowCursor)
+}
@@ -0,0 +1,372 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// KittyKeyboardFlags are the progressive enhancements of the kitty keyboard
+// protocol, see https://sw.kovidgoyal.net/kitty/keyboard-protocol/
+type KittyKeyboardFlags int
+
+const (
+	// KittyDisambiguate reports keys that are ambiguous otherwise, such as
+	// Ctrl+I and Tab, or Esc, unambiguously
+	KittyDisambiguate KittyKeyboardFlags = 1 << iota
+	// KittyReportEvents reports the repeating and releasing of keys
+	KittyReportEvents
+	// KittyReportAlternates reports the shifted and base layout keys
+	KittyReportAlternates
+	// KittyReportAllKeys reports all keys as escape sequences, including
+	// text keys and the modifier keys themselves
+	KittyReportAllKeys
+	// KittyReportText reports the text produced by keys
+	KittyReportText
+)
+
+const (
+	// query the flags in effect, and ask for the primary device
+	// attributes as every terminal answers that, the reply to which
+	// arriving first means the kitty protocol is not supported
+	kittyKeyboardQuery = "\x1b[?u\x1b[c"
+	// pop the flags pushed by kittyKeyboardPush
+	kittyKeyboardPop = "\x1b[<u"
+)
+
+// kittyKeyboardPush returns the escape sequence to push the flags given
+// onto the terminal's stack of keyboard modes
+func kittyKeyboardPush(flags KittyKeyboardFlags) string {
+	return fmt.Sprintf("\x1b[>%du", int(flags))
+}
+
+// EnableKittyKeyboard asks the terminal whether it supports the kitty
+// keyboard protocol, and if it answers that it does, enables the
+// enhancements given.  Keys are parsed as they are otherwise until then,
+// and after if the terminal does not answer.
+func (t *cDisplay) EnableKittyKeyboard(flags KittyKeyboardFlags) {
+	t.Lock()
+	defer t.Unlock()
+	if t.finished || !t.xtermLike() || flags == 0 {
+		return
+	}
+	t.kittyFlags = flags
+	if t.kittyActive {
+		// replace the flags pushed already
+		t.writeString(fmt.Sprintf("\x1b[=%d;1u", int(flags)))
+		return
+	}
+	t.kittyQueried = true
+	t.writeString(kittyKeyboardQuery)
+}
+
+// DisableKittyKeyboard restores the keyboard mode of the terminal to what
+// it was before the kitty keyboard protocol was enabled.
+func (t *cDisplay) DisableKittyKeyboard() {
+	t.Lock()
+	defer t.Unlock()
+	t.kittyFlags = 0
+	t.kittyQueried = false
+	t.disableKittyKeyboard()
+}
+
+func (t *cDisplay) disableKittyKeyboard() {
+	if t.kittyActive {
+		t.writeString(kittyKeyboardPop)
+		t.kittyActive = false
+	}
+}
+
+// HasKittyKeyboard returns true if keys are reported with the kitty keyboard
+// protocol
+func (t *cDisplay) HasKittyKeyboard() bool {
+	t.Lock()
+	defer t.Unlock()
+	return t.kittyActive
+}
+
+// parseKittyReply parses the replies to the kitty keyboard query, either
+// CSI ? flags u from terminals that support the protocol, or the primary
+// device attributes CSI ? attributes c
+func (t *cDisplay) parseKittyReply(buf *bytes.Buffer) (bool, bool) {
+	b := buf.Bytes()
+	prefix := []byte("\x1b[?")
+	if len(b) < len(prefix) {
+		return bytes.HasPrefix(prefix, b), false
+	}
+	if !bytes.HasPrefix(b, prefix) {
+		return false, false
+	}
+	for i := len(prefix); i < len(b); i++ {
+		switch c := b[i]; {
+		case c >= '0' && c <= '9':
+		case c == ';' && i > len(prefix):
+		case c == 'u' && i > len(prefix) && bytes.IndexByte(b[len(prefix):i], ';') < 0:
+			buf.Next(i + 1)
+			if t.kittyQueried {
+				t.kittyQueried = false
+				t.kittyActive = true
+				t.writeString(kittyKeyboardPush(t.kittyFlags))
+			}
+			return true, true
+		case c == 'c':
+			buf.Next(i + 1)
+			if t.kittyQueried {
+				t.kittyQueried = false
+				TraceF("kitty keyboard protocol not supported, using legacy keys")
+			}
+			return true, true
+		default:
+			return false, false
+		}
+	}
+	return true, false
+}
+
+// parseKittyKey parses the keys reported with the kitty keyboard protocol,
+// of the form CSI code:shifted:base ; modifiers:event ; text u, as well as
+// the legacy forms CSI number ; modifiers:event ~ and CSI 1 ; modifiers:event
+// followed by one of ABCDEFHPQRS
+func (t *cDisplay) parseKittyKey(buf *bytes.Buffer, evs *[]Event) (bool, bool) {
+	if !t.kittyActive {
+		return false, false
+	}
+	b := buf.Bytes()
+	prefix := []byte("\x1b[")
+	if len(b) < len(prefix) {
+		return bytes.HasPrefix(prefix, b), false
+	}
+	if !bytes.HasPrefix(b, prefix) {
+		return false, false
+	}
+	for i := len(prefix); i < len(b); i++ {
+		c := b[i]
+		if (c >= '0' && c <= '9') || c == ';' || c == ':' {
+			continue
+		}
+		if !strings.ContainsRune("u~ABCDEFHPQRS", rune(c)) {
+			return false, false
+		}
+		params := strings.Split(string(b[len(prefix):i]), ";")
+		keys := kittyParams(params[0])
+		if c == '~' && len(keys) > 0 && (keys[0] == 200 || keys[0] == 201) {
+			// bracketed paste
+			return false, false
+		}
+		if c == 'u' && len(keys) == 0 {
+			// not a key, such as restoring the cursor
+			return false, false
+		}
+		buf.Next(i + 1)
+		var mods, text []int
+		if len(params) > 1 {
+			mods = kittyParams(params[1])
+		}
+		if len(params) > 2 {
+			text = kittyParams(params[2])
+		}
+		if ev := kittyKeyEvent(c, keys, mods, text); ev != nil {
+			*evs = append(*evs, ev)
+		}
+		return true, true
+	}
+	return true, false
+}
+
+// kittyParams returns the numbers of a parameter separated by colons, empty
+// numbers are zero
+func kittyParams(param string) (values []int) {
+	if param == "" {
+		return nil
+	}
+	for _, field := range strings.Split(param, ":") {
+		v, _ := strconv.Atoi(field)
+		values = append(values, v)
+	}
+	return
+}
+
+var (
+	// the keys reported as CSI number ~
+	kittyTildeKeys = map[int]Key{
+		2:  KeyInsert,
+		3:  KeyDelete,
+		5:  KeyPgUp,
+		6:  KeyPgDn,
+		7:  KeyHome,
+		8:  KeyEnd,
+		11: KeyF1,
+		12: KeyF2,
+		13: KeyF3,
+		14: KeyF4,
+		15: KeyF5,
+		17: KeyF6,
+		18: KeyF7,
+		19: KeyF8,
+		20: KeyF9,
+		21: KeyF10,
+		23: KeyF11,
+		24: KeyF12,
+	}
+	// the keys reported as CSI 1 letter
+	kittyLetterKeys = map[byte]Key{
+		'A': KeyUp,
+		'B': KeyDown,
+		'C': KeyRight,
+		'D': KeyLeft,
+		'E': KeyCenter,
+		'F': KeyEnd,
+		'H': KeyHome,
+		'P': KeyF1,
+		'Q': KeyF2,
+		'R': KeyF3,
+		'S': KeyF4,
+	}
+	// the keys reported as CSI code u, of the private use area
+	kittyFunctionalKeys = map[int]Key{
+		57361: KeyPrint,
+		57362: KeyPause,
+		57414: KeyEnter,
+		57417: KeyLeft,
+		57418: KeyRight,
+		57419: KeyUp,
+		57420: KeyDown,
+		57421: KeyPgUp,
+		57422: KeyPgDn,
+		57423: KeyHome,
+		57424: KeyEnd,
+		57425: KeyInsert,
+		57426: KeyDelete,
+		57427: KeyCenter,
+	}
+	// the text of the keypad keys reported as CSI code u
+	kittyKeypadRunes = map[int]rune{
+		57409: '.',
+		57410: '/',
+		57411: '*',
+		57412: '-',
+		57413: '+',
+		57415: '=',
+		57416: ',',
+	}
+)
+
+// kittyKeyEvent returns the EventKey for a key reported with the kitty
+// keyboard protocol, or nil for those that have no equivalent, such as the
+// modifier keys themselves
+func kittyKeyEvent(final byte, keys, mods, text []int) *EventKey {
+	code, shifted, base := 1, 0, 0
+	if len(keys) > 0 {
+		code = keys[0]
+	}
+	if len(keys) > 1 {
+		shifted = keys[1]
+	}
+	if len(keys) > 2 {
+		base = keys[2]
+	}
+	bits, event := 0, KeyEventPress
+	if len(mods) > 0 && mods[0] > 0 {
+		bits = mods[0] - 1
+	}
+	if len(mods) > 1 {
+		switch mods[1] {
+		case 2:
+			event = KeyEventRepeat
+		case 3:
+			event = KeyEventRelease
+		}
+	}
+	mod := ModNone
+	for bit, m := range []ModMask{ModShift, ModAlt, ModCtrl, ModSuper, ModHyper, ModMeta} {
+		if bits&(1<<uint(bit)) != 0 {
+			mod |= m
+		}
+	}
+	newKey := func(k Key, ch rune) *EventKey {
+		ev := NewEventKeyType(k, ch, mod, event)
+		ev.shifted = rune(shifted)
+		ev.base = rune(base)
+		return ev
+	}
+
+	switch final {
+	case '~':
+		if k, ok := kittyTildeKeys[code]; ok {
+			return newKey(k, 0)
+		}
+		return nil
+	case 'u':
+	default:
+		if k, ok := kittyLetterKeys[final]; ok {
+			return newKey(k, 0)
+		}
+		return nil
+	}
+
+	switch {
+	case code == 27:
+		return newKey(KeyEsc, rune(code))
+	case code == 13:
+		return newKey(KeyEnter, rune(code))
+	case code == 9:
+		if mod&ModShift != 0 {
+			return newKey(KeyBacktab, 0)
+		}
+		return newKey(KeyTab, rune(code))
+	case code == 127:
+		return newKey(KeyBackspace2, rune(code))
+	case code == 8:
+		return newKey(KeyBackspace, rune(code))
+	case code >= 57376 && code <= 57398:
+		return newKey(KeyF13+Key(code-57376), 0)
+	case code >= 57399 && code <= 57408:
+		return newKey(KeyRune, rune('0'+code-57399))
+	}
+	if k, ok := kittyFunctionalKeys[code]; ok {
+		return newKey(k, 0)
+	}
+	if r, ok := kittyKeypadRunes[code]; ok {
+		return newKey(KeyRune, r)
+	}
+	if code >= 57344 && code <= 63743 {
+		// modifier, lock and media keys
+		return nil
+	}
+	if mod&ModCtrl != 0 && mod&(ModSuper|ModHyper|ModMeta) == 0 {
+		if k, ok := controlKey(rune(code)); ok {
+			switch k {
+			case KeyTab, KeyEnter, KeyEsc, KeyBackspace:
+				// told apart from Ctrl+Tab and the like
+			default:
+				return newKey(k, rune(k))
+			}
+		}
+	}
+	ch := rune(code)
+	switch {
+	case len(text) > 0 && text[0] > 0:
+		ch = rune(text[0])
+	case mod&ModShift != 0 && shifted != 0:
+		ch = rune(shifted)
+	case mod&ModShift != 0:
+		ch = unicode.ToUpper(ch)
+	}
+	return newKey(KeyRune, ch)
+}
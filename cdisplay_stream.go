@@ -0,0 +1,84 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"io"
+)
+
+// StreamDisplay is a Display that is driven through an io.ReadWriter, such
+// as a network connection, instead of a local terminal device.  There is no
+// way to query the size of the remote terminal, so the owner of the stream
+// must report it with SetWindowSize whenever it changes.
+type StreamDisplay interface {
+	// SetWindowSize notifies the display that the remote terminal is now
+	// the given width and height, in character cells.  If the display is
+	// running, a resize event is posted and the display redrawn.
+	SetWindowSize(w, h int)
+
+	Display
+}
+
+// NewStreamDisplay returns a StreamDisplay for the named terminal type (as
+// would normally be found in $TERM), reading input from and rendering output
+// to the given stream.  The stream is assumed to carry UTF-8 and the initial
+// window size is taken from the terminfo description until SetWindowSize is
+// called.
+func NewStreamDisplay(termName string, stream io.ReadWriter) (StreamDisplay, error) {
+	t, err := newTerminfoDisplay(termName)
+	if err != nil {
+		return nil, err
+	}
+	t.stream = stream
+	t.charset = "UTF-8"
+	t.streamW = t.ti.Columns
+	t.streamH = t.ti.Lines
+	return t, nil
+}
+
+func (t *cDisplay) SetWindowSize(w, h int) {
+	t.Lock()
+	t.streamW, t.streamH = w, h
+	t.Unlock()
+	select {
+	case t.resizeQ <- struct{}{}:
+	default:
+		// a resize is already pending
+	}
+}
+
+// initializeStream is the stream equivalent of initialize, there is no
+// terminal mode to configure as that is the concern of the remote end.
+func (t *cDisplay) initializeStream() error {
+	t.in = t.stream
+	t.out = t.stream
+	t.Lock()
+	if w, h, err := t.getStreamSize(); err == nil {
+		t.cells.Resize(w, h)
+	}
+	t.Unlock()
+	return nil
+}
+
+// finalizeStream is the stream equivalent of finalize.  The stream itself is
+// not closed, that is left to the owner of the stream.
+func (t *cDisplay) finalizeStream() {
+	<-t.inDoneQ
+}
+
+// getStreamSize returns the last window size reported for the stream.
+func (t *cDisplay) getStreamSize() (int, int, error) {
+	return t.streamW, t.streamH, nil
+}
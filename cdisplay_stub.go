@@ -35,6 +35,14 @@ func (t *cDisplay) initialize() error {
 func (t *cDisplay) finalize() {
 }
 
+func (t *cDisplay) stopJob() bool {
+	return false
+}
+
+func (t *cDisplay) waitInput(fd int) (bool, error) {
+	return true, nil
+}
+
 func (t *cDisplay) getWinSize() (int, int, error) {
 	return 0, 0, ErrNoScreen
 }
@@ -0,0 +1,112 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+// Suspend hands the terminal back to the user's shell: the alternate screen
+// is left, mouse, paste and focus reporting are turned off and the terminal
+// modes the display was started with are restored.  Nothing is drawn until
+// the display is resumed.
+func (t *cDisplay) Suspend() error {
+	t.Lock()
+	defer t.Unlock()
+	if t.finished {
+		return ErrNoDisplay
+	}
+	if t.suspended {
+		return nil
+	}
+	ti := t.ti
+	t.endHyperlink()
+	t.restoreTitle()
+	// the user's cursor is restored, the application's when resumed
+	style, color := t.cursorStyle, t.cursorColor
+	t.cursorStyle, t.cursorColor = CursorStyleDefault, ColorDefault
+	t.sendCursorStyle()
+	t.cursorStyle, t.cursorColor = style, color
+	if t.inline {
+		t.inlineFinish()
+	} else {
+		t.TPuts(ti.ShowCursor)
+		t.TPuts(ti.AttrOff)
+		t.TPuts(ti.Clear)
+		t.TPuts(ti.ExitCA)
+	}
+	t.TPuts(ti.ExitKeypad)
+	t.TPuts(t.disablePaste)
+	t.TPuts(t.disableFocus)
+	t.disableKittyKeyboard()
+	t.disableMouse()
+	t.curStyle = styleInvalid
+	t.suspended = true
+	if t.stream == nil {
+		t.disengage()
+	}
+	return nil
+}
+
+// Resume takes the terminal back from the user's shell, undoing Suspend, and
+// redraws the whole display.  Displays that were not suspended are put back
+// in raw mode and redrawn all the same, the terminal modes may have been
+// changed while the process was stopped.
+func (t *cDisplay) Resume() error {
+	t.Lock()
+	if t.finished {
+		t.Unlock()
+		return ErrNoDisplay
+	}
+	if t.stream == nil {
+		if err := t.engage(); err != nil {
+			t.Unlock()
+			return err
+		}
+	}
+	if t.suspended {
+		ti := t.ti
+		if t.inline {
+			t.TPuts(ti.HideCursor)
+			t.TPuts(ti.EnableAcs)
+			t.initInline()
+		} else {
+			t.TPuts(ti.EnterCA)
+			t.TPuts(ti.HideCursor)
+			t.TPuts(ti.EnableAcs)
+			t.TPuts(ti.Clear)
+		}
+		if t.pasteOn {
+			t.TPuts(t.enablePaste)
+		}
+		if t.focusOn {
+			t.TPuts(t.enableFocus)
+		}
+		if t.mouseFlags != 0 {
+			t.EnableMouse(t.mouseFlags)
+		}
+		if t.kittyFlags != 0 && t.xtermLike() {
+			// the reply pushes the flags again
+			t.kittyQueried = true
+			t.writeString(kittyKeyboardQuery)
+		}
+		t.suspended = false
+	}
+	t.Unlock()
+	t.Sync()
+	return nil
+}
+
+// hasJobControl returns true if the display is on the terminal the process
+// was started from, which job control stops the process along with
+func (t *cDisplay) hasJobControl() bool {
+	return t.stream == nil && t.ttyPath == "" && t.tty != nil
+}
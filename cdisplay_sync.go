@@ -0,0 +1,137 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// SyncOutputMode determines whether the output of each frame is wrapped in
+// synchronized update sequences (DEC private mode 2026), such that the
+// terminal shows the whole frame at once instead of tearing.
+type SyncOutputMode int
+
+const (
+	// SyncOutputAuto synchronizes output when the terminal supports
+	// synchronized updates, as its terminfo says or it reports when queried
+	// with DECRQM as the display is initialized
+	SyncOutputAuto SyncOutputMode = iota
+	// SyncOutputEnabled always synchronizes output
+	SyncOutputEnabled
+	// SyncOutputDisabled never synchronizes output
+	SyncOutputDisabled
+)
+
+func (m SyncOutputMode) String() string {
+	switch m {
+	case SyncOutputAuto:
+		return "auto"
+	case SyncOutputEnabled:
+		return "enabled"
+	case SyncOutputDisabled:
+		return "disabled"
+	}
+	return fmt.Sprintf("sync-output-mode(%d)", int(m))
+}
+
+const (
+	// DEC private mode for synchronized updates
+	decModeSyncOutput = 2026
+	// begin and end synchronized update, BSU and ESU
+	syncOutputBegin = "\x1b[?2026h"
+	syncOutputEnd   = "\x1b[?2026l"
+)
+
+// decrqmSequence returns the escape sequence to request the state of the
+// given DEC private mode, the reply is parsed by parseModeReport
+func decrqmSequence(mode int) string {
+	return fmt.Sprintf("\x1b[?%d$p", mode)
+}
+
+func (t *cDisplay) SetSyncOutput(mode SyncOutputMode) {
+	t.Lock()
+	defer t.Unlock()
+	t.syncMode = mode
+}
+
+// HasSyncOutput returns true if the output of each frame is synchronized
+func (t *cDisplay) HasSyncOutput() bool {
+	t.Lock()
+	defer t.Unlock()
+	return t.syncOutput()
+}
+
+func (t *cDisplay) syncOutput() bool {
+	switch t.syncMode {
+	case SyncOutputEnabled:
+		return true
+	case SyncOutputDisabled:
+		return false
+	}
+	return t.syncCapable
+}
+
+// querySyncOutput asks the terminal whether it supports synchronized updates,
+// unless its terminfo has the Sync extension saying so already.  Terminals
+// that do not understand DECRQM are expected to ignore it.
+func (t *cDisplay) querySyncOutput() {
+	if !t.syncCapable {
+		t.writeString(decrqmSequence(decModeSyncOutput))
+	}
+}
+
+// parseModeReport parses a DECRPM report, the reply to a DECRQM query, of
+// the form: CSI ? mode ; value $ y
+func (t *cDisplay) parseModeReport(buf *bytes.Buffer) (bool, bool) {
+	b := buf.Bytes()
+	prefix := []byte("\x1b[?")
+	if len(b) < len(prefix) {
+		return bytes.HasPrefix(prefix, b), false
+	}
+	if !bytes.HasPrefix(b, prefix) {
+		return false, false
+	}
+	mode, value, state := 0, 0, 0
+	for i := len(prefix); i < len(b); i++ {
+		c := b[i]
+		switch {
+		case state == 0 && c >= '0' && c <= '9':
+			mode = (mode * 10) + int(c-'0')
+		case state == 0 && c == ';':
+			state = 1
+		case state == 1 && c >= '0' && c <= '9':
+			value = (value * 10) + int(c-'0')
+		case state == 1 && c == '$':
+			state = 2
+		case state == 2 && c == 'y':
+			buf.Next(i + 1)
+			t.modeReport(mode, value)
+			return true, true
+		default:
+			return false, false
+		}
+	}
+	return true, false
+}
+
+// modeReport handles the reported value of a DEC private mode: 0 for modes
+// not recognized, 1 set, 2 reset, 3 permanently set and 4 permanently reset
+func (t *cDisplay) modeReport(mode, value int) {
+	switch mode {
+	case decModeSyncOutput:
+		t.syncCapable = value >= 1 && value <= 3
+	}
+}
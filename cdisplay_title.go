@@ -0,0 +1,78 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	// XTWINOPS, save and restore both the icon name and window title
+	titlePush    = "\x1b[22;0t"
+	titleRestore = "\x1b[23;0t"
+)
+
+// titleSequence returns the escape sequence, OSC 0, setting both the icon
+// name and window title of the terminal to the title given
+func titleSequence(title string) string {
+	return "\x1b]0;" + sanitizeTitle(title) + "\x07"
+}
+
+// sanitizeTitle removes the control characters from the title given, any of
+// which could end the escape sequence the title is written within
+func sanitizeTitle(title string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, title)
+}
+
+// SetTitle sets the window title and icon name of the terminal.  Terminals
+// like xterm are sent OSC 0, and have the user's own title saved with the
+// first title set and restored when the display is closed.  Others are sent
+// the title within the status line capabilities of terminfo, tsl and fsl, if
+// the terminal has them.
+func (t *cDisplay) SetTitle(title string) {
+	t.Lock()
+	defer t.Unlock()
+	if t.finished {
+		return
+	}
+	t.title = title
+	switch {
+	case t.xtermLike():
+		if !t.titlePushed {
+			t.writeString(titlePush)
+			t.titlePushed = true
+		}
+		t.writeString(titleSequence(title))
+	case t.tsl != "":
+		t.TPuts(t.ti.TParm(t.tsl, 0))
+		t.writeString(sanitizeTitle(title))
+		t.TPuts(t.fsl)
+	}
+}
+
+// restoreTitle restores the title the user had before the first title was
+// set, if saved
+func (t *cDisplay) restoreTitle() {
+	if t.titlePushed {
+		t.writeString(titleRestore)
+		t.titlePushed = false
+	}
+}
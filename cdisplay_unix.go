@@ -20,20 +20,33 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"golang.org/x/sys/unix"
 	"golang.org/x/term"
 )
 
+// TtyResizePollInterval is how often the size of a display opened on an
+// explicit tty path is checked for changes.  SIGWINCH is only delivered for
+// the controlling terminal, so displays on any other tty or pty device need
+// to watch for resizes themselves.
+var TtyResizePollInterval = time.Millisecond * 250
+
+// TtyInputPollInterval is the longest a display waits for input from its tty
+// before checking whether the input is held, for another program to read the
+// tty instead.  See DisplayManager.RunExternal.
+var TtyInputPollInterval = time.Millisecond * 100
+
 // engage is used to place the terminal in raw mode and establish screen size, etc.
 // Thing of this is as CDK "engaging" the clutch, as it's going to be driving the
 // terminal interface.
 func (t *cDisplay) engage() error {
 
-	stdin := int(t.in.Fd())
-	if _, err := term.MakeRaw(stdin); err != nil {
+	fd := int(t.tty.Fd())
+	if _, err := term.MakeRaw(fd); err != nil {
 		return err
 	}
-	if w, h, err := term.GetSize(stdin); err == nil && w != 0 && h != 0 {
+	if w, h, err := term.GetSize(fd); err == nil && w != 0 && h != 0 {
 		t.cells.Resize(w, h)
 	}
 	return nil
@@ -44,45 +57,134 @@ func (t *cDisplay) engage() error {
 // can take over the terminal interface.  This restores the TTY mode that was
 // present when the application was first started.
 func (t *cDisplay) disengage() {
-	if t.in != nil {
-		term.Restore(int(t.in.Fd()), t.saved)
+	if t.tty != nil {
+		term.Restore(int(t.tty.Fd()), t.saved)
 	}
 }
 
 // initialize is used at application startup, and sets up the initial values
 // including file descriptors used for terminals and saving the initial state
 // so that it can be restored when the application terminates.
+//
+// When the display has a ttyPath, that device is opened and used for both
+// input and output instead of the process stdin and stdout.
 func (t *cDisplay) initialize() error {
 	var err error
-	fd := int(os.Stdin.Fd())
-	t.in = os.Stdin
-	t.out = os.Stdout
+	if t.ttyPath != "" {
+		if t.tty, err = os.OpenFile(t.ttyPath, os.O_RDWR, 0); err != nil {
+			return err
+		}
+		t.in = t.tty
+		t.out = t.tty
+	} else {
+		t.tty = os.Stdin
+		t.in = os.Stdin
+		t.out = os.Stdout
+	}
+	fd := int(t.tty.Fd())
 	t.saved, err = term.GetState(fd)
 	if err != nil {
+		t.closeTty()
 		return err
 	}
 	signal.Notify(t.sigwinch, syscall.SIGWINCH)
+	if t.ttyPath == "" {
+		// job control is for the controlling terminal only
+		signal.Notify(t.sigtstp, syscall.SIGTSTP)
+		signal.Notify(t.sigcont, syscall.SIGCONT)
+	}
 
 	if err := t.engage(); err != nil {
+		t.closeTty()
 		return err
 	}
+	if t.ttyPath != "" {
+		w, h, _ := t.getWinSize()
+		go t.resizePollLoop(w, h)
+	}
 	return nil
 }
 
+// resizePollLoop watches the size of an explicitly opened tty and notifies
+// the main loop whenever it changes, standing in for SIGWINCH.
+func (t *cDisplay) resizePollLoop(pw, ph int) {
+	ticker := time.NewTicker(TtyResizePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.inDoneQ:
+			return
+		case <-ticker.C:
+			if w, h, err := t.getWinSize(); err == nil && (w != pw || h != ph) {
+				pw, ph = w, h
+				select {
+				case t.resizeQ <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// closeTty closes the tty device if one was opened by initialize.
+func (t *cDisplay) closeTty() {
+	if t.ttyPath != "" && t.tty != nil {
+		_ = t.tty.Close()
+		t.tty = nil
+	}
+}
+
 // finalize is used to at application shutdown, and restores the terminal
 // to it's initial state.  It should not be called more than once.
 func (t *cDisplay) finalize() {
 
 	signal.Stop(t.sigwinch)
+	signal.Stop(t.sigtstp)
+	signal.Stop(t.sigcont)
 
 	<-t.inDoneQ
+	// the input is read until quitting, the tty is only closed once no
+	// longer read
+	<-t.inputDoneQ
 
 	t.disengage()
+	t.closeTty()
+}
+
+// stopJob stops the process group, as the shell does when Ctrl+Z is typed
+// in cooked mode, if the display is on the controlling terminal, returning
+// false otherwise.  The process is stopped asynchronously, and continues when
+// sent SIGCONT, which is posted as an EventSuspend.
+func (t *cDisplay) stopJob() bool {
+	if !t.hasJobControl() {
+		return false
+	}
+	// SIGTSTP is notified, and so would not stop the process
+	if err := syscall.Kill(0, syscall.SIGSTOP); err != nil {
+		return false
+	}
+	return true
+}
+
+// waitInput waits up to TtyInputPollInterval for the tty to have input to
+// read, returning false if it has none yet.
+func (t *cDisplay) waitInput(fd int) (bool, error) {
+	fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+	n, err := unix.Poll(fds, int(TtyInputPollInterval/time.Millisecond))
+	switch {
+	case err == unix.EINTR:
+		return false, nil
+	case err != nil:
+		return false, err
+	case n > 0 && fds[0].Revents&unix.POLLNVAL != 0:
+		return false, os.ErrClosed
+	}
+	return n > 0, nil
 }
 
 // getWinSize is called to obtain the terminal dimensions.
 func (t *cDisplay) getWinSize() (int, int, error) {
-	return term.GetSize(int(t.in.Fd()))
+	return term.GetSize(int(t.tty.Fd()))
 }
 
 // Beep emits a beep to the terminal.
@@ -0,0 +1,729 @@
+// +build linux
+
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// testPty is a pty pair, the master side is held open by the test while
+// the display under test opens the slave side by path
+type testPty struct {
+	master    *os.File
+	slavePath string
+	output    bytes.Buffer
+
+	sync.Mutex
+}
+
+func openTestPty(w, h int) (*testPty, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	fd := int(master.Fd())
+	if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		_ = master.Close()
+		return nil, err
+	}
+	n, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		_ = master.Close()
+		return nil, err
+	}
+	p := &testPty{
+		master:    master,
+		slavePath: fmt.Sprintf("/dev/pts/%d", n),
+	}
+	if err := p.SetSize(w, h); err != nil {
+		_ = master.Close()
+		return nil, err
+	}
+	go p.drain()
+	return p, nil
+}
+
+func (p *testPty) SetSize(w, h int) error {
+	ws := &unix.Winsize{Col: uint16(w), Row: uint16(h)}
+	return unix.IoctlSetWinsize(int(p.master.Fd()), unix.TIOCSWINSZ, ws)
+}
+
+func (p *testPty) drain() {
+	buf := make([]byte, 4096)
+	for {
+		n, err := p.master.Read(buf)
+		if n > 0 {
+			p.Lock()
+			p.output.Write(buf[:n])
+			p.Unlock()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (p *testPty) Output() string {
+	p.Lock()
+	defer p.Unlock()
+	return p.output.String()
+}
+
+func (p *testPty) Close() {
+	_ = p.master.Close()
+}
+
+func pollTestEvent(d Display, timeout time.Duration, match func(evt Event) bool) Event {
+	found := make(chan Event, 1)
+	go func() {
+		for {
+			evt := d.PollEvent()
+			if evt == nil || match(evt) {
+				found <- evt
+				return
+			}
+		}
+	}()
+	select {
+	case evt := <-found:
+		return evt
+	case <-time.After(timeout):
+		return nil
+	}
+}
+
+func waitForTestOutput(p *testPty, timeout time.Duration, text string) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if bytes.Contains([]byte(p.Output()), []byte(text)) {
+			return true
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	return false
+}
+
+func TestDisplayTtyPath(t *testing.T) {
+	Convey("Driving a display through a pty", t, func() {
+		p, err := openTestPty(40, 10)
+		if err != nil {
+			SkipSo(err, ShouldBeNil)
+			return
+		}
+		defer p.Close()
+		prevTerm := os.Getenv("TERM")
+		os.Setenv("TERM", "xterm")
+		defer os.Setenv("TERM", prevTerm)
+
+		d, err := NewDisplayWithTtyPath(p.slavePath)
+		So(err, ShouldBeNil)
+		So(d, ShouldNotBeNil)
+		So(d.Init(), ShouldBeNil)
+		defer d.Close()
+		w, h := d.Size()
+		So(w, ShouldEqual, 40)
+		So(h, ShouldEqual, 10)
+
+		Convey("input written to the pty master arrives as events", func() {
+			_, err := p.master.Write([]byte("a"))
+			So(err, ShouldBeNil)
+			evt := pollTestEvent(d, time.Second, func(evt Event) bool {
+				_, ok := evt.(*EventKey)
+				return ok
+			})
+			So(evt, ShouldNotBeNil)
+			So(evt.(*EventKey).Rune(), ShouldEqual, 'a')
+		})
+
+		Convey("content shown is rendered to the pty", func() {
+			d.SetContent(0, 0, 'Z', nil, StyleDefault)
+			d.Show()
+			So(waitForTestOutput(p, time.Second, "Z"), ShouldBeTrue)
+		})
+
+		Convey("resizing the pty emits a resize event", func() {
+			So(p.SetSize(50, 12), ShouldBeNil)
+			evt := pollTestEvent(d, time.Second*2, func(evt Event) bool {
+				if er, ok := evt.(*EventResize); ok {
+					w, h := er.Size()
+					return w == 50 && h == 12
+				}
+				return false
+			})
+			So(evt, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Opening a missing tty path fails", t, func() {
+		prevTerm := os.Getenv("TERM")
+		os.Setenv("TERM", "xterm")
+		defer os.Setenv("TERM", prevTerm)
+		d, err := NewDisplayWithTtyPath("/dev/cdk-no-such-tty")
+		So(err, ShouldBeNil)
+		So(d.Init(), ShouldNotBeNil)
+	})
+}
+
+func TestInlineDisplay(t *testing.T) {
+	Convey("Rendering inline on a pty", t, func() {
+		p, err := openTestPty(40, 10)
+		if err != nil {
+			SkipSo(err, ShouldBeNil)
+			return
+		}
+		defer p.Close()
+		prevTerm := os.Getenv("TERM")
+		os.Setenv("TERM", "xterm")
+		defer os.Setenv("TERM", prevTerm)
+
+		Convey("with a fixed height", func() {
+			d, err := NewInlineDisplay(p.slavePath, 3)
+			So(err, ShouldBeNil)
+			So(d.Init(), ShouldBeNil)
+			w, h := d.Size()
+			So(w, ShouldEqual, 40)
+			So(h, ShouldEqual, 3)
+			d.SetContent(2, 1, 'Q', nil, StyleDefault)
+			d.Show()
+			So(waitForTestOutput(p, time.Second, "Q"), ShouldBeTrue)
+			d.Close()
+			So(waitForTestOutput(p, time.Second, "\x1b[?25h"), ShouldBeTrue)
+			out := p.Output()
+			// never switches to the alternate screen or clears it
+			So(out, ShouldNotContainSubstring, "\x1b[?1049h")
+			So(out, ShouldNotContainSubstring, "\x1b[H\x1b[2J")
+			// reserves the rows, then moves relative to them
+			So(out, ShouldContainSubstring, "\r\n\n")
+			So(out, ShouldContainSubstring, "\x1b[2A\r")
+			So(out, ShouldContainSubstring, "\x1b[1B\r  Q")
+			// leaves the cursor on the line after the region
+			So(out, ShouldContainSubstring, "\x1b[m\r\n\r")
+		})
+
+		Convey("sized to the content", func() {
+			d, err := NewInlineDisplay(p.slavePath, InlineContentHeight)
+			So(err, ShouldBeNil)
+			So(d.Init(), ShouldBeNil)
+			w, h := d.Size()
+			So(w, ShouldEqual, 40)
+			So(h, ShouldEqual, 10)
+			d.SetContent(0, 0, 'A', nil, StyleDefault)
+			d.SetContent(0, 1, 'B', nil, StyleDefault)
+			d.Show()
+			So(waitForTestOutput(p, time.Second, "B"), ShouldBeTrue)
+			out := p.Output()
+			So(out, ShouldNotContainSubstring, "\n\n")
+			d.SetContent(0, 1, ' ', nil, StyleDefault)
+			d.Show()
+			So(waitForTestOutput(p, time.Second, "\x1b[J"), ShouldBeTrue)
+			d.Close()
+		})
+
+		Convey("resizing the terminal redraws the region", func() {
+			d, err := NewInlineDisplay(p.slavePath, 2)
+			So(err, ShouldBeNil)
+			So(d.Init(), ShouldBeNil)
+			defer d.Close()
+			d.SetContent(0, 0, 'R', nil, StyleDefault)
+			d.Show()
+			So(p.SetSize(30, 10), ShouldBeNil)
+			evt := pollTestEvent(d, time.Second*2, func(evt Event) bool {
+				if er, ok := evt.(*EventResize); ok {
+					w, h := er.Size()
+					return w == 30 && h == 2
+				}
+				return false
+			})
+			So(evt, ShouldNotBeNil)
+			So(waitForTestOutput(p, time.Second, "\x1b[J"), ShouldBeTrue)
+		})
+	})
+}
+
+func TestDisplayCursorStyle(t *testing.T) {
+	Convey("Setting the cursor style on a pty", t, func() {
+		p, err := openTestPty(20, 5)
+		if err != nil {
+			SkipSo(err, ShouldBeNil)
+			return
+		}
+		defer p.Close()
+		prevTerm := os.Getenv("TERM")
+		os.Setenv("TERM", "xterm")
+		defer os.Setenv("TERM", prevTerm)
+
+		d, err := NewDisplayWithTtyPath(p.slavePath)
+		So(err, ShouldBeNil)
+		So(d.Init(), ShouldBeNil)
+		d.ShowCursor(1, 1)
+		d.SetCursorStyle(CursorStyleSteadyBar)
+		d.SetCursorColor(ColorBlue)
+		d.Show()
+		So(waitForTestOutput(p, time.Second, "\x1b[6 q"), ShouldBeTrue)
+		So(waitForTestOutput(p, time.Second, "\x1b]12;#0000ff\x07"), ShouldBeTrue)
+		d.Close()
+		// reset with Se, as xterm has it
+		So(waitForTestOutput(p, time.Second, "\x1b[2 q"), ShouldBeTrue)
+		So(waitForTestOutput(p, time.Second, "\x1b]112\x07"), ShouldBeTrue)
+	})
+}
+
+func TestDisplaySyncOutput(t *testing.T) {
+	Convey("Synchronizing output on a pty", t, func() {
+		p, err := openTestPty(20, 5)
+		if err != nil {
+			SkipSo(err, ShouldBeNil)
+			return
+		}
+		defer p.Close()
+		prevTerm := os.Getenv("TERM")
+		os.Setenv("TERM", "xterm")
+		defer os.Setenv("TERM", prevTerm)
+
+		d, err := NewDisplayWithTtyPath(p.slavePath)
+		So(err, ShouldBeNil)
+		So(d.Init(), ShouldBeNil)
+		defer d.Close()
+		So(waitForTestOutput(p, time.Second, "\x1b[?2026$p"), ShouldBeTrue)
+		So(d.HasSyncOutput(), ShouldBeFalse)
+
+		// the terminal reports the mode as reset, which is supported
+		_, err = p.master.Write([]byte("\x1b[?2026;2$y"))
+		So(err, ShouldBeNil)
+		deadline := time.Now().Add(time.Second)
+		for !d.HasSyncOutput() && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond * 10)
+		}
+		So(d.HasSyncOutput(), ShouldBeTrue)
+		d.SetContent(0, 0, 'Z', nil, StyleDefault)
+		d.Show()
+		So(waitForTestOutput(p, time.Second, "\x1b[?2026l"), ShouldBeTrue)
+		out := p.Output()
+		begin := bytes.Index([]byte(out), []byte("\x1b[?2026h"))
+		end := bytes.Index([]byte(out), []byte("\x1b[?2026l"))
+		So(begin, ShouldBeGreaterThan, -1)
+		So(bytes.Index([]byte(out[begin:end]), []byte("Z")), ShouldBeGreaterThan, -1)
+
+		Convey("unless overridden", func() {
+			d.SetSyncOutput(SyncOutputDisabled)
+			So(d.HasSyncOutput(), ShouldBeFalse)
+			d.SetSyncOutput(SyncOutputAuto)
+			So(d.HasSyncOutput(), ShouldBeTrue)
+		})
+
+		Convey("the report is not delivered as keys", func() {
+			_, err = p.master.Write([]byte("\x1b[?2026;0$yq"))
+			So(err, ShouldBeNil)
+			evt := pollTestEvent(d, time.Second, func(evt Event) bool {
+				_, ok := evt.(*EventKey)
+				return ok
+			})
+			So(evt, ShouldNotBeNil)
+			So(evt.(*EventKey).Rune(), ShouldEqual, 'q')
+			So(d.HasSyncOutput(), ShouldBeFalse)
+		})
+	})
+}
+
+func TestDisplayClipboard(t *testing.T) {
+	Convey("Using the clipboard on a pty", t, func() {
+		p, err := openTestPty(20, 5)
+		if err != nil {
+			SkipSo(err, ShouldBeNil)
+			return
+		}
+		defer p.Close()
+		prevTerm := os.Getenv("TERM")
+		os.Setenv("TERM", "xterm")
+		defer os.Setenv("TERM", prevTerm)
+
+		d, err := NewDisplayWithTtyPath(p.slavePath)
+		So(err, ShouldBeNil)
+		So(d.Init(), ShouldBeNil)
+		defer d.Close()
+
+		So(d.SetClipboard(SelectionClipboard, "over ssh"), ShouldBeNil)
+		So(waitForTestOutput(p, time.Second, "\x1b]52;c;b3ZlciBzc2g=\x07"), ShouldBeTrue)
+		So(d.RequestClipboard(SelectionPrimary), ShouldBeNil)
+		So(waitForTestOutput(p, time.Second, "\x1b]52;p;?\x07"), ShouldBeTrue)
+
+		isClipboard := func(evt Event) bool {
+			_, ok := evt.(*EventClipboard)
+			return ok
+		}
+		// terminated with BEL
+		_, err = p.master.Write([]byte("\x1b]52;p;cGFzdGVk\x07"))
+		So(err, ShouldBeNil)
+		evt := pollTestEvent(d, time.Second, isClipboard)
+		So(evt, ShouldNotBeNil)
+		So(evt.(*EventClipboard).Selection(), ShouldEqual, SelectionPrimary)
+		So(evt.(*EventClipboard).Text(), ShouldEqual, "pasted")
+		// terminated with ST
+		_, err = p.master.Write([]byte("\x1b]52;c;YWdhaW4=\x1b\\"))
+		So(err, ShouldBeNil)
+		evt = pollTestEvent(d, time.Second, isClipboard)
+		So(evt, ShouldNotBeNil)
+		So(evt.(*EventClipboard).Selection(), ShouldEqual, SelectionClipboard)
+		So(evt.(*EventClipboard).Text(), ShouldEqual, "again")
+	})
+}
+
+func TestDisplayHyperlink(t *testing.T) {
+	Convey("Drawing hyperlinks on a pty", t, func() {
+		p, err := openTestPty(20, 5)
+		if err != nil {
+			SkipSo(err, ShouldBeNil)
+			return
+		}
+		defer p.Close()
+		prevTerm := os.Getenv("TERM")
+		os.Setenv("TERM", "xterm")
+		defer os.Setenv("TERM", prevTerm)
+
+		d, err := NewDisplayWithTtyPath(p.slavePath)
+		So(err, ShouldBeNil)
+		So(d.Init(), ShouldBeNil)
+		defer d.Close()
+
+		link := StyleDefault.Url("https://example.com").UrlId("ex")
+		for i, r := range "abc" {
+			d.SetContent(i, 0, r, nil, link)
+		}
+		d.SetContent(3, 0, 'd', nil, StyleDefault)
+		d.Show()
+		So(waitForTestOutput(p, time.Second, "\x1b]8;id=ex;https://example.com\x1b\\"), ShouldBeTrue)
+		So(waitForTestOutput(p, time.Second, "abc\x1b]8;;\x1b\\"), ShouldBeTrue)
+	})
+}
+
+func TestDisplayTitle(t *testing.T) {
+	Convey("Setting the title on a pty", t, func() {
+		p, err := openTestPty(20, 5)
+		if err != nil {
+			SkipSo(err, ShouldBeNil)
+			return
+		}
+		defer p.Close()
+		prevTerm := os.Getenv("TERM")
+		os.Setenv("TERM", "xterm")
+		defer os.Setenv("TERM", prevTerm)
+
+		d, err := NewDisplayWithTtyPath(p.slavePath)
+		So(err, ShouldBeNil)
+		So(d.Init(), ShouldBeNil)
+
+		d.SetTitle("first\x07 title")
+		So(waitForTestOutput(p, time.Second, titlePush+"\x1b]0;first title\x07"), ShouldBeTrue)
+		d.SetTitle("second")
+		So(waitForTestOutput(p, time.Second, "\x1b]0;second\x07"), ShouldBeTrue)
+		So(strings.Count(p.Output(), titlePush), ShouldEqual, 1)
+		d.Close()
+		So(waitForTestOutput(p, time.Second, titleRestore), ShouldBeTrue)
+	})
+	Convey("Decoding terminfo capabilities", t, func() {
+		So(unescapeTerminfo(`\E]0;`), ShouldEqual, "\x1b]0;")
+		So(unescapeTerminfo(`^G\007\s\,^?`), ShouldEqual, "\x07\x07 ,\x7f")
+		So(unescapeTerminfo(`\0`), ShouldEqual, "\x80")
+	})
+	Convey("Reading the capabilities tcell's terminfo lacks", t, func() {
+		var tc termcap
+		So(tc.parse("sun|Sun,\n\ths,\n\ttsl=\\E]l,\n\tfsl=\\E\\\\,\n\tSs=\\E[%p1%d q,\n"), ShouldBeNil)
+		extras := tc.extras()
+		So(extras.toStatusLine, ShouldEqual, "\x1b]l")
+		So(extras.fromStatusLine, ShouldEqual, "\x1b\\")
+		So(extras.setCursorStyle, ShouldEqual, "\x1b[%p1%d q")
+		So(extras.resetCursorStyle, ShouldBeEmpty)
+		// without the hs flag there is no status line
+		So(tc.parse("sun|Sun,\n\ttsl=\\E]l,\n\tfsl=\\E\\\\,\n"), ShouldBeNil)
+		extras = tc.extras()
+		So(extras.toStatusLine, ShouldBeEmpty)
+		So(extras.fromStatusLine, ShouldBeEmpty)
+	})
+}
+
+func TestDisplayFocus(t *testing.T) {
+	Convey("Reporting focus on a pty", t, func() {
+		p, err := openTestPty(20, 5)
+		if err != nil {
+			SkipSo(err, ShouldBeNil)
+			return
+		}
+		defer p.Close()
+		prevTerm := os.Getenv("TERM")
+		os.Setenv("TERM", "xterm")
+		defer os.Setenv("TERM", prevTerm)
+
+		d, err := NewDisplayWithTtyPath(p.slavePath)
+		So(err, ShouldBeNil)
+		So(d.Init(), ShouldBeNil)
+		defer d.Close()
+
+		d.EnableFocus()
+		So(waitForTestOutput(p, time.Second, "\x1b[?1004h"), ShouldBeTrue)
+		isFocus := func(evt Event) bool {
+			_, ok := evt.(*EventFocus)
+			return ok
+		}
+		_, err = p.master.Write([]byte("\x1b[O"))
+		So(err, ShouldBeNil)
+		evt := pollTestEvent(d, time.Second, isFocus)
+		So(evt, ShouldNotBeNil)
+		So(evt.(*EventFocus).Focused(), ShouldBeFalse)
+		_, err = p.master.Write([]byte("\x1b[I"))
+		So(err, ShouldBeNil)
+		evt = pollTestEvent(d, time.Second, isFocus)
+		So(evt, ShouldNotBeNil)
+		So(evt.(*EventFocus).Focused(), ShouldBeTrue)
+		d.DisableFocus()
+		So(waitForTestOutput(p, time.Second, "\x1b[?1004l"), ShouldBeTrue)
+	})
+}
+
+func TestDisplaySuspend(t *testing.T) {
+	Convey("Suspending a display on a pty", t, func() {
+		p, err := openTestPty(20, 5)
+		if err != nil {
+			SkipSo(err, ShouldBeNil)
+			return
+		}
+		defer p.Close()
+		prevTerm := os.Getenv("TERM")
+		os.Setenv("TERM", "xterm")
+		defer os.Setenv("TERM", prevTerm)
+
+		d, err := NewDisplayWithTtyPath(p.slavePath)
+		So(err, ShouldBeNil)
+		So(d.Init(), ShouldBeNil)
+		defer d.Close()
+		echo := func() bool {
+			tio, err := unix.IoctlGetTermios(int(p.master.Fd()), unix.TCGETS)
+			So(err, ShouldBeNil)
+			return tio.Lflag&unix.ECHO != 0
+		}
+
+		d.EnableMouse()
+		d.EnablePaste()
+		So(waitForTestOutput(p, time.Second, "\x1b[?1003h"), ShouldBeTrue)
+		So(echo(), ShouldBeFalse)
+		So(d.Suspend(), ShouldBeNil)
+		So(waitForTestOutput(p, time.Second, "\x1b[?1000l\x1b[?1002l\x1b[?1003l\x1b[?1006l"), ShouldBeTrue)
+		So(waitForTestOutput(p, time.Second, "\x1b[?2004l"), ShouldBeTrue)
+		So(echo(), ShouldBeTrue)
+		d.SetContent(0, 0, 'z', nil, StyleDefault)
+		d.Show()
+		time.Sleep(time.Millisecond * 50)
+		So(p.Output(), ShouldNotContainSubstring, "z")
+		So(d.Resume(), ShouldBeNil)
+		So(echo(), ShouldBeFalse)
+		So(waitForTestOutput(p, time.Second, "z"), ShouldBeTrue)
+		So(strings.Count(p.Output(), "\x1b[?1003h"), ShouldEqual, 2)
+		So(strings.Count(p.Output(), "\x1b[?2004h"), ShouldEqual, 2)
+	})
+}
+
+func TestDisplayManagerRunExternalPty(t *testing.T) {
+	Convey("Running an external program on a pty", t, func() {
+		p, err := openTestPty(20, 5)
+		if err != nil {
+			SkipSo(err, ShouldBeNil)
+			return
+		}
+		defer p.Close()
+		prevTerm := os.Getenv("TERM")
+		os.Setenv("TERM", "xterm")
+		defer os.Setenv("TERM", prevTerm)
+
+		d := NewDisplayManager("external", p.slavePath)
+		defer d.Destroy()
+		d.CaptureDisplay(p.slavePath)
+		So(waitForTestOutput(p, time.Second, "\x1b[?1003h"), ShouldBeTrue)
+		display := d.Display()
+
+		go func() {
+			// typed once the program is waiting for it
+			if waitForTestOutput(p, time.Second, "ready") {
+				_, _ = p.master.Write([]byte("typed\n"))
+			}
+		}()
+		cmd := exec.Command("sh", "-c", "echo ready; read line; echo \"got $line\"")
+		So(d.RunExternal(cmd), ShouldBeNil)
+		So(p.Output(), ShouldContainSubstring, "got typed")
+		So(d.IsSuspended(), ShouldBeFalse)
+		So(d.Display(), ShouldEqual, display)
+
+		_, _ = p.master.Write([]byte("k"))
+		keys := make(chan *EventKey, 1)
+		go func() {
+			// the resize and other events queued are skipped
+			for evt := display.PollEvent(); evt != nil; evt = display.PollEvent() {
+				if e, ok := evt.(*EventKey); ok {
+					keys <- e
+					return
+				}
+			}
+		}()
+		select {
+		case e := <-keys:
+			So(e.Rune(), ShouldEqual, 'k')
+		case <-time.After(time.Second):
+			So("no key event", ShouldBeEmpty)
+		}
+		So(strings.Count(p.Output(), "\x1b[?1003h"), ShouldEqual, 2)
+		So(strings.Count(p.Output(), "\x1b[?2004h"), ShouldEqual, 2)
+	})
+}
+
+func TestDisplayKittyKeyboard(t *testing.T) {
+	open := func() (*testPty, Display) {
+		p, err := openTestPty(20, 5)
+		if err != nil {
+			SkipSo(err, ShouldBeNil)
+			return nil, nil
+		}
+		prevTerm := os.Getenv("TERM")
+		os.Setenv("TERM", "xterm")
+		defer os.Setenv("TERM", prevTerm)
+		d, err := NewDisplayWithTtyPath(p.slavePath)
+		So(err, ShouldBeNil)
+		So(d.Init(), ShouldBeNil)
+		return p, d
+	}
+	isKey := func(evt Event) bool {
+		_, ok := evt.(*EventKey)
+		return ok
+	}
+	sendKey := func(p *testPty, d Display, input string) *EventKey {
+		_, err := p.master.Write([]byte(input))
+		So(err, ShouldBeNil)
+		evt := pollTestEvent(d, time.Second, isKey)
+		So(evt, ShouldNotBeNil)
+		return evt.(*EventKey)
+	}
+	Convey("Using the kitty keyboard protocol on a pty", t, func() {
+		p, d := open()
+		if p == nil {
+			return
+		}
+		defer p.Close()
+		flags := KittyDisambiguate | KittyReportEvents | KittyReportAlternates
+		d.EnableKittyKeyboard(flags)
+		So(waitForTestOutput(p, time.Second, kittyKeyboardQuery), ShouldBeTrue)
+		// the reply is followed by the device attributes
+		_, err := p.master.Write([]byte("\x1b[?0u\x1b[?62;22c"))
+		So(err, ShouldBeNil)
+		So(waitForTestOutput(p, time.Second, kittyKeyboardPush(flags)), ShouldBeTrue)
+		So(d.HasKittyKeyboard(), ShouldBeTrue)
+
+		ev := sendKey(p, d, "\x1b[27u")
+		So(ev.Key(), ShouldEqual, KeyEsc)
+		ev = sendKey(p, d, "\x1b[105;5u")
+		So(ev.Key(), ShouldEqual, KeyRune)
+		So(ev.Rune(), ShouldEqual, 'i')
+		So(ev.Modifiers(), ShouldEqual, ModCtrl)
+		ev = sendKey(p, d, "\x1b[9;5u")
+		So(ev.Key(), ShouldEqual, KeyTab)
+		So(ev.Modifiers(), ShouldEqual, ModCtrl)
+		ev = sendKey(p, d, "\x1b[9u")
+		So(ev.Key(), ShouldEqual, KeyTab)
+		So(ev.Modifiers(), ShouldEqual, ModNone)
+		ev = sendKey(p, d, "\x1b[97:65;2:3u")
+		So(ev.Key(), ShouldEqual, KeyRune)
+		So(ev.Rune(), ShouldEqual, 'A')
+		So(ev.Modifiers(), ShouldEqual, ModShift)
+		So(ev.EventType(), ShouldEqual, KeyEventRelease)
+		ev = sendKey(p, d, "\x1b[1;9:2A")
+		So(ev.Key(), ShouldEqual, KeyUp)
+		So(ev.Modifiers(), ShouldEqual, ModSuper)
+		So(ev.EventType(), ShouldEqual, KeyEventRepeat)
+		// bracketed paste is still recognized
+		_, err = p.master.Write([]byte("\x1b[200~"))
+		So(err, ShouldBeNil)
+		evt := pollTestEvent(d, time.Second, func(evt Event) bool {
+			_, ok := evt.(*EventPaste)
+			return ok
+		})
+		So(evt, ShouldNotBeNil)
+
+		d.Close()
+		So(waitForTestOutput(p, time.Second, kittyKeyboardPop), ShouldBeTrue)
+	})
+	Convey("Falling back to legacy keys", t, func() {
+		p, d := open()
+		if p == nil {
+			return
+		}
+		defer p.Close()
+		defer d.Close()
+		d.EnableKittyKeyboard(KittyDisambiguate)
+		So(waitForTestOutput(p, time.Second, kittyKeyboardQuery), ShouldBeTrue)
+		_, err := p.master.Write([]byte("\x1b[?62;22c"))
+		So(err, ShouldBeNil)
+		ev := sendKey(p, d, "\x1b[1;5A")
+		So(ev.Key(), ShouldEqual, KeyUp)
+		So(ev.Modifiers(), ShouldEqual, ModCtrl)
+		So(d.HasKittyKeyboard(), ShouldBeFalse)
+		So(strings.Contains(p.Output(), kittyKeyboardPush(KittyDisambiguate)), ShouldBeFalse)
+	})
+	Convey("Decoding kitty keys", t, func() {
+		// Ctrl+I and Ctrl+Tab, Ctrl+M and Ctrl+Enter are told apart
+		ctrlI := kittyKeyEvent('u', []int{105}, []int{5}, nil)
+		ctrlTab := kittyKeyEvent('u', []int{9}, []int{5}, nil)
+		So(ctrlI.Key(), ShouldEqual, KeyRune)
+		So(ctrlI.Rune(), ShouldEqual, 'i')
+		So(ctrlI.Modifiers(), ShouldEqual, ModCtrl)
+		So(ctrlTab.Key(), ShouldEqual, KeyTab)
+		So(ctrlTab.Modifiers(), ShouldEqual, ModCtrl)
+		ev := kittyKeyEvent('u', []int{109}, []int{5}, nil)
+		So(ev.Key(), ShouldEqual, KeyRune)
+		So(ev.Rune(), ShouldEqual, 'm')
+		So(ev.Modifiers(), ShouldEqual, ModCtrl)
+		ev = kittyKeyEvent('u', []int{13}, []int{5}, nil)
+		So(ev.Key(), ShouldEqual, KeyEnter)
+		So(ev.Modifiers(), ShouldEqual, ModCtrl)
+		ev = kittyKeyEvent('u', []int{97}, []int{5}, nil)
+		So(ev.Key(), ShouldEqual, KeyCtrlA)
+		ev = kittyKeyEvent('u', []int{13}, nil, nil)
+		So(ev.Key(), ShouldEqual, KeyEnter)
+		So(ev.Modifiers(), ShouldEqual, ModNone)
+		ev = kittyKeyEvent('u', []int{1092, 0, 97}, []int{5}, nil)
+		So(ev.Key(), ShouldEqual, KeyRune)
+		So(ev.Rune(), ShouldEqual, 'ф')
+		So(ev.BaseRune(), ShouldEqual, 'a')
+		ev = kittyKeyEvent('u', []int{97}, []int{1 + 2 + 16 + 32}, []int{229})
+		So(ev.Rune(), ShouldEqual, 'å')
+		So(ev.Modifiers(), ShouldEqual, ModAlt|ModHyper|ModMeta)
+		ev = kittyKeyEvent('u', []int{9}, []int{2}, nil)
+		So(ev.Key(), ShouldEqual, KeyBacktab)
+		ev = kittyKeyEvent('~', []int{15}, []int{3}, nil)
+		So(ev.Key(), ShouldEqual, KeyF5)
+		So(ev.Modifiers(), ShouldEqual, ModAlt)
+		So(kittyKeyEvent('u', []int{57441}, []int{2}, nil), ShouldBeNil)
+		So(kittyKeyEvent('u', []int{57400}, nil, nil).Rune(), ShouldEqual, '1')
+		So(kittyKeyEvent('u', []int{57376}, nil, nil).Key(), ShouldEqual, KeyF13)
+	})
+}
@@ -72,6 +72,8 @@ type CApp struct {
 	context *cli.Context
 	cli     *cli.App
 	initFn  DisplayInitFn
+	keyMap  *KeyMapFile
+	keyDump string
 	valid   bool
 }
 
@@ -158,7 +160,10 @@ func (app *CApp) Version() string {
 }
 
 func (app *CApp) InitUI() error {
-	return app.initFn(app.DisplayManager())
+	if err := app.initFn(app.DisplayManager()); err != nil {
+		return err
+	}
+	return app.applyKeyMap()
 }
 
 func (app *CApp) AddFlag(f cli.Flag) {
@@ -240,5 +245,45 @@ func (app *CApp) MainActionFn(c *cli.Context) error {
 			}
 		}
 	}
+	if Build.Recording {
+		if v := c.String("cdk-record"); !utils.IsEmpty(v) {
+			f, err := os.Create(v)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			app.display.RecordTo(f)
+		}
+		if v := c.String("cdk-replay"); !utils.IsEmpty(v) {
+			replay, err := LoadReplayFile(v)
+			if err != nil {
+				return err
+			}
+			speed := c.Float64("cdk-replay-speed")
+			go func() {
+				if err := replay.Play(app.display, speed); err != nil {
+					Error(err)
+				}
+			}()
+		}
+	}
+	if Build.Screenshots {
+		if v := c.String("cdk-screenshot"); !utils.IsEmpty(v) {
+			if _, err := ScreenshotFormatFromPath(v); err != nil {
+				return err
+			}
+			app.display.SetScreenshotPath(v)
+		}
+	}
+	if Build.KeyMaps {
+		if v := c.String("cdk-keymap"); !utils.IsEmpty(v) {
+			keyMap, err := LoadKeyMapFile(v)
+			if err != nil {
+				return err
+			}
+			app.keyMap = keyMap
+		}
+		app.keyDump = c.String("cdk-keymap-dump")
+	}
 	return app.DisplayManager().Run()
 }
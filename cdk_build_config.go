@@ -28,12 +28,18 @@ type Config struct {
 	LogTimestamps      bool
 	LogTimestampFormat bool
 	LogOutput          bool
+	Recording          bool
+	Screenshots        bool
+	KeyMaps            bool
 }
 
 var Build = Config{
-	LogFile:   true,
-	LogLevel:  true,
-	LogLevels: true,
+	LogFile:     true,
+	LogLevel:    true,
+	LogLevels:   true,
+	Recording:   true,
+	Screenshots: true,
+	KeyMaps:     true,
 }
 
 func getCdkCliFlags() (flags []cli.Flag) {
@@ -64,5 +70,14 @@ func getCdkCliFlags() (flags []cli.Flag) {
 	if Build.LogOutput {
 		flags = append(flags, cdkLogOutputFlag)
 	}
+	if Build.Recording {
+		flags = append(flags, cdkRecordFlag, cdkReplayFlag, cdkReplaySpeedFlag)
+	}
+	if Build.Screenshots {
+		flags = append(flags, cdkScreenshotFlag)
+	}
+	if Build.KeyMaps {
+		flags = append(flags, cdkKeymapFlag, cdkKeymapDumpFlag)
+	}
 	return
 }
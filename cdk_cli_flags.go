@@ -87,4 +87,41 @@ var (
 		Value: false,
 		Usage: "list the levels of logging verbosity",
 	}
+	cdkRecordFlag = &cli.StringFlag{
+		Name:    "cdk-record",
+		EnvVars: []string{"GO_CDK_RECORD"},
+		Value:   "",
+		Usage:   "record the session to the given asciicast v2 file path",
+	}
+	cdkReplayFlag = &cli.StringFlag{
+		Name:    "cdk-replay",
+		EnvVars: []string{"GO_CDK_REPLAY"},
+		Value:   "",
+		Usage:   "replay the input events of the given asciicast v2 file path",
+	}
+	cdkReplaySpeedFlag = &cli.Float64Flag{
+		Name:        "cdk-replay-speed",
+		EnvVars:     []string{"GO_CDK_REPLAY_SPEED"},
+		Value:       1.0,
+		Usage:       "replay speed multiplier, zero replays without any delays",
+		DefaultText: "1.0",
+	}
+	cdkScreenshotFlag = &cli.StringFlag{
+		Name:    "cdk-screenshot",
+		EnvVars: []string{"GO_CDK_SCREENSHOT"},
+		Value:   "",
+		Usage:   "save a screenshot to the given .ans, .html or .svg file path when F12 is pressed",
+	}
+	cdkKeymapFlag = &cli.StringFlag{
+		Name:    "cdk-keymap",
+		EnvVars: []string{"GO_CDK_KEYMAP"},
+		Value:   "",
+		Usage:   "load key bindings from the given JSON, YAML or TOML file path",
+	}
+	cdkKeymapDumpFlag = &cli.StringFlag{
+		Name:    "cdk-keymap-dump",
+		EnvVars: []string{"GO_CDK_KEYMAP_DUMP"},
+		Value:   "",
+		Usage:   "write the effective key bindings to the given file path",
+	}
 )
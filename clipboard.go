@@ -0,0 +1,123 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+var (
+	// ClipboardMaxSize is the most bytes of text that can be written to a
+	// clipboard selection, many terminals refuse anything much larger
+	ClipboardMaxSize = 100000
+)
+
+// ClipboardSelection names one of the clipboards of the system, the values
+// are those used by the OSC 52 escape sequence.
+type ClipboardSelection string
+
+const (
+	// SelectionClipboard is the clipboard used by explicit copy and paste
+	SelectionClipboard ClipboardSelection = "c"
+	// SelectionPrimary is the selection of X11 and Wayland, pasted with the
+	// middle mouse button
+	SelectionPrimary ClipboardSelection = "p"
+)
+
+func (s ClipboardSelection) String() string {
+	switch s {
+	case SelectionClipboard:
+		return "clipboard"
+	case SelectionPrimary:
+		return "primary"
+	}
+	return fmt.Sprintf("selection(%s)", string(s))
+}
+
+// IsValid returns true if the selection is one of the known selections
+func (s ClipboardSelection) IsValid() bool {
+	return s == SelectionClipboard || s == SelectionPrimary
+}
+
+// Clipboard reads and writes the clipboard selections of the user's system,
+// through the display.  Reading is asynchronous, the text is delivered with
+// an EventClipboard, if the display permits reading the clipboard at all.
+type Clipboard interface {
+	SetText(selection ClipboardSelection, text string) error
+	RequestText(selection ClipboardSelection) error
+}
+
+// CClipboard is the Clipboard of a display manager, using whichever display
+// the display manager has captured at the time
+type CClipboard struct {
+	manager DisplayManager
+}
+
+func NewClipboard(manager DisplayManager) *CClipboard {
+	return &CClipboard{manager: manager}
+}
+
+func (c *CClipboard) display() (Display, error) {
+	if c.manager != nil {
+		if display := c.manager.Display(); display != nil {
+			return display, nil
+		}
+	}
+	return nil, fmt.Errorf("display not captured or otherwise missing")
+}
+
+// SetText replaces the contents of the given selection with the text given
+func (c *CClipboard) SetText(selection ClipboardSelection, text string) error {
+	display, err := c.display()
+	if err != nil {
+		return err
+	}
+	return display.SetClipboard(selection, text)
+}
+
+// RequestText asks for the contents of the given selection, which are
+// delivered with an EventClipboard
+func (c *CClipboard) RequestText(selection ClipboardSelection) error {
+	display, err := c.display()
+	if err != nil {
+		return err
+	}
+	return display.RequestClipboard(selection)
+}
+
+// checkClipboard returns an error if the given selection or text cannot be
+// written to the clipboard
+func checkClipboard(selection ClipboardSelection, text string) error {
+	if !selection.IsValid() {
+		return fmt.Errorf("invalid clipboard selection: %q", string(selection))
+	}
+	if len(text) > ClipboardMaxSize {
+		return fmt.Errorf("clipboard text of %d bytes exceeds %d bytes", len(text), ClipboardMaxSize)
+	}
+	return nil
+}
+
+// osc52Sequence returns the escape sequence to set the given selection to
+// the text given
+func osc52Sequence(selection ClipboardSelection, text string) string {
+	return fmt.Sprintf("\x1b]52;%s;%s\x07", string(selection), base64.StdEncoding.EncodeToString([]byte(text)))
+}
+
+// osc52RequestSequence returns the escape sequence to ask for the contents
+// of the given selection
+func osc52RequestSequence(selection ClipboardSelection) string {
+	return fmt.Sprintf("\x1b]52;%s;?\x07", string(selection))
+}
@@ -0,0 +1,67 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClipboard(t *testing.T) {
+	Convey("Clipboard selections", t, func() {
+		So(SelectionClipboard.String(), ShouldEqual, "clipboard")
+		So(SelectionPrimary.IsValid(), ShouldBeTrue)
+		So(ClipboardSelection("x").IsValid(), ShouldBeFalse)
+		So(osc52Sequence(SelectionClipboard, "hi"), ShouldEqual, "\x1b]52;c;aGk=\x07")
+		So(osc52RequestSequence(SelectionPrimary), ShouldEqual, "\x1b]52;p;?\x07")
+	})
+
+	Convey("The clipboard of a display manager", t, func() {
+		d := NewDisplayManager("clipboard", OffscreenDisplayTtyPath)
+		defer d.Destroy()
+		clipboard := d.Clipboard()
+		So(clipboard, ShouldNotBeNil)
+		So(clipboard.SetText(SelectionClipboard, "early"), ShouldNotBeNil)
+		d.CaptureDisplay(OffscreenDisplayTtyPath)
+		o := d.Display().(OffscreenDisplay)
+
+		So(clipboard.SetText(SelectionClipboard, "copied"), ShouldBeNil)
+		So(clipboard.SetText(SelectionPrimary, "selected"), ShouldBeNil)
+		So(o.GetClipboard(SelectionClipboard), ShouldEqual, "copied")
+		So(o.GetClipboard(SelectionPrimary), ShouldEqual, "selected")
+		So(clipboard.SetText("x", "nope"), ShouldNotBeNil)
+		So(clipboard.SetText(SelectionClipboard, strings.Repeat("x", ClipboardMaxSize+1)), ShouldNotBeNil)
+		So(o.GetClipboard(SelectionClipboard), ShouldEqual, "copied")
+
+		So(clipboard.RequestText(SelectionPrimary), ShouldBeNil)
+		evt := o.PollEvent()
+		ec, ok := evt.(*EventClipboard)
+		So(ok, ShouldBeTrue)
+		So(ec.Selection(), ShouldEqual, SelectionPrimary)
+		So(ec.Text(), ShouldEqual, "selected")
+
+		var received string
+		d.Connect(SignalEventClipboard, "clipboard-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+			if e, ok := argv[1].(*EventClipboard); ok {
+				received = e.Text()
+			}
+			return EVENT_STOP
+		})
+		So(d.ProcessEvent(ec), ShouldEqual, EVENT_STOP)
+		So(received, ShouldEqual, "selected")
+	})
+}
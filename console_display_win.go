@@ -256,6 +256,19 @@ func (s *cConsoleDisplay) EnablePaste() {}
 
 func (s *cConsoleDisplay) DisablePaste() {}
 
+func (s *cConsoleDisplay) EnableFocus() {}
+
+func (s *cConsoleDisplay) DisableFocus() {}
+
+// EnableKittyKeyboard does nothing, the console reports key events itself
+func (s *cConsoleDisplay) EnableKittyKeyboard(KittyKeyboardFlags) {}
+
+func (s *cConsoleDisplay) DisableKittyKeyboard() {}
+
+func (s *cConsoleDisplay) HasKittyKeyboard() bool {
+	return false
+}
+
 func (s *cConsoleDisplay) Close() {
 	s.finiOnce.Do(s.finish)
 }
@@ -378,6 +391,56 @@ func (s *cConsoleDisplay) HideCursor() {
 	s.ShowCursor(-1, -1)
 }
 
+func (s *cConsoleDisplay) SetCursorStyle(style CursorStyle) {
+	s.Lock()
+	if s.vten && style.IsValid() {
+		s.emitVtString(style.sequence())
+	}
+	s.Unlock()
+}
+
+func (s *cConsoleDisplay) SetClipboard(selection ClipboardSelection, text string) error {
+	if err := checkClipboard(selection, text); err != nil {
+		return err
+	}
+	s.Lock()
+	defer s.Unlock()
+	if !s.vten {
+		return fmt.Errorf("clipboard not supported by the console")
+	}
+	s.emitVtString(osc52Sequence(selection, text))
+	return nil
+}
+
+// RequestClipboard is not supported, the console does not report the
+// clipboard contents
+func (s *cConsoleDisplay) RequestClipboard(selection ClipboardSelection) error {
+	return fmt.Errorf("clipboard not supported by the console")
+}
+
+func (s *cConsoleDisplay) SetTitle(title string) {
+	s.Lock()
+	if s.vten {
+		s.emitVtString(titleSequence(title))
+	}
+	s.Unlock()
+}
+
+// SetSyncOutput does nothing, the console is updated with each write
+func (s *cConsoleDisplay) SetSyncOutput(SyncOutputMode) {}
+
+func (s *cConsoleDisplay) HasSyncOutput() bool {
+	return false
+}
+
+func (s *cConsoleDisplay) SetCursorColor(color Color) {
+	s.Lock()
+	if s.vten {
+		s.emitVtString(cursorColorSequence(color))
+	}
+	s.Unlock()
+}
+
 type inputRecord struct {
 	typ  uint16
 	_    uint16
@@ -940,6 +1003,17 @@ func (s *cConsoleDisplay) Sync() {
 	s.Unlock()
 }
 
+// Suspend does nothing, the console is not taken over from the shell.
+func (s *cConsoleDisplay) Suspend() error {
+	return nil
+}
+
+// Resume redraws the whole console.
+func (s *cConsoleDisplay) Resume() error {
+	s.Sync()
+	return nil
+}
+
 type consoleInfo struct {
 	size  coord
 	pos   coord
@@ -1,3 +1,128 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
 package cdk
 
+import (
+	"fmt"
+)
+
 // the blinking box, solid/empty, blinking line, that thing
+
+// CursorStyle is the shape of the cursor and whether it blinks.  The values
+// are those used by the DECSCUSR escape sequence, CursorStyleDefault being
+// whatever the user has configured their terminal to use.
+type CursorStyle int
+
+const (
+	CursorStyleDefault CursorStyle = iota
+	CursorStyleBlinkingBlock
+	CursorStyleSteadyBlock
+	CursorStyleBlinkingUnderline
+	CursorStyleSteadyUnderline
+	CursorStyleBlinkingBar
+	CursorStyleSteadyBar
+)
+
+func (s CursorStyle) String() string {
+	switch s {
+	case CursorStyleDefault:
+		return "default"
+	case CursorStyleBlinkingBlock:
+		return "blinking-block"
+	case CursorStyleSteadyBlock:
+		return "steady-block"
+	case CursorStyleBlinkingUnderline:
+		return "blinking-underline"
+	case CursorStyleSteadyUnderline:
+		return "steady-underline"
+	case CursorStyleBlinkingBar:
+		return "blinking-bar"
+	case CursorStyleSteadyBar:
+		return "steady-bar"
+	}
+	return fmt.Sprintf("cursor-style(%d)", int(s))
+}
+
+// IsValid returns true if the style is one of the known cursor styles
+func (s CursorStyle) IsValid() bool {
+	return s >= CursorStyleDefault && s <= CursorStyleSteadyBar
+}
+
+// IsBlinking returns true if the style is one of the blinking styles, the
+// blinking of CursorStyleDefault is not known.
+func (s CursorStyle) IsBlinking() bool {
+	switch s {
+	case CursorStyleBlinkingBlock, CursorStyleBlinkingUnderline, CursorStyleBlinkingBar:
+		return true
+	}
+	return false
+}
+
+// Blinking returns the blinking variant of the style's shape, the steady
+// variant if blink is false.  CursorStyleDefault is returned unchanged.
+func (s CursorStyle) Blinking(blink bool) CursorStyle {
+	if s == CursorStyleDefault || !s.IsValid() {
+		return s
+	}
+	steady := s + s%2
+	if blink {
+		return steady - 1
+	}
+	return steady
+}
+
+// escape sequence to set the cursor style, DECSCUSR
+func (s CursorStyle) sequence() string {
+	return fmt.Sprintf("\x1b[%d q", int(s))
+}
+
+// cursorColorSequence returns the escape sequence to set the color of the
+// cursor, OSC 12, or to reset the cursor color, OSC 112, for colors that are
+// not valid or have no RGB value
+func cursorColorSequence(color Color) string {
+	if v := color.Hex(); v >= 0 {
+		return fmt.Sprintf("\x1b]12;#%06x\x07", v)
+	}
+	return "\x1b]112\x07"
+}
+
+// Cursor describes the cursor a window wants displayed, its position is
+// relative to the region of the window.
+type Cursor struct {
+	X       int
+	Y       int
+	Visible bool
+	Style   CursorStyle
+	Color   Color
+}
+
+// MakeCursor returns a visible Cursor at the given position, in the given
+// style and with the terminal's default cursor color.
+func MakeCursor(x, y int, style CursorStyle) Cursor {
+	return Cursor{
+		X:       x,
+		Y:       y,
+		Visible: true,
+		Style:   style,
+		Color:   ColorDefault,
+	}
+}
+
+// HiddenCursor is the cursor used by windows that have not asked for one
+var HiddenCursor = Cursor{X: -1, Y: -1}
+
+func (c Cursor) String() string {
+	return fmt.Sprintf("{x:%v,y:%v,visible:%v,style:%v,color:%v}", c.X, c.Y, c.Visible, c.Style, c.Color)
+}
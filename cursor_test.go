@@ -0,0 +1,140 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCursorStyle(t *testing.T) {
+	Convey("Cursor styles", t, func() {
+		So(CursorStyleDefault.String(), ShouldEqual, "default")
+		So(CursorStyleSteadyBar.String(), ShouldEqual, "steady-bar")
+		So(CursorStyle(42).IsValid(), ShouldBeFalse)
+		So(CursorStyleBlinkingUnderline.IsBlinking(), ShouldBeTrue)
+		So(CursorStyleSteadyUnderline.IsBlinking(), ShouldBeFalse)
+		So(CursorStyleDefault.IsBlinking(), ShouldBeFalse)
+		So(CursorStyleBlinkingBlock.Blinking(false), ShouldEqual, CursorStyleSteadyBlock)
+		So(CursorStyleSteadyBlock.Blinking(false), ShouldEqual, CursorStyleSteadyBlock)
+		So(CursorStyleSteadyBar.Blinking(true), ShouldEqual, CursorStyleBlinkingBar)
+		So(CursorStyleBlinkingBar.Blinking(true), ShouldEqual, CursorStyleBlinkingBar)
+		So(CursorStyleDefault.Blinking(true), ShouldEqual, CursorStyleDefault)
+		So(CursorStyleSteadyUnderline.sequence(), ShouldEqual, "\x1b[4 q")
+		So(cursorColorSequence(ColorRed), ShouldEqual, "\x1b]12;#ff0000\x07")
+		So(cursorColorSequence(ColorDefault), ShouldEqual, "\x1b]112\x07")
+		c := MakeCursor(1, 2, CursorStyleSteadyBar)
+		So(c.Visible, ShouldBeTrue)
+		So(c.Color, ShouldEqual, ColorDefault)
+		So(HiddenCursor.Visible, ShouldBeFalse)
+	})
+}
+
+func TestOffscreenCursor(t *testing.T) {
+	Convey("Offscreen cursor state", t, func() {
+		o, err := MakeOffscreenDisplay("")
+		So(err, ShouldBeNil)
+		o.SetSize(10, 4)
+		style, color := o.GetCursorStyle()
+		So(style, ShouldEqual, CursorStyleDefault)
+		So(color, ShouldEqual, ColorDefault)
+		o.ShowCursor(2, 3)
+		o.SetCursorStyle(CursorStyleBlinkingUnderline)
+		o.SetCursorColor(ColorLime)
+		x, y, visible := o.GetCursor()
+		So(x, ShouldEqual, 2)
+		So(y, ShouldEqual, 3)
+		So(visible, ShouldBeTrue)
+		style, color = o.GetCursorStyle()
+		So(style, ShouldEqual, CursorStyleBlinkingUnderline)
+		So(color, ShouldEqual, ColorLime)
+		o.SetCursorStyle(CursorStyle(-3))
+		style, _ = o.GetCursorStyle()
+		So(style, ShouldEqual, CursorStyleDefault)
+		o.HideCursor()
+		_, _, visible = o.GetCursor()
+		So(visible, ShouldBeFalse)
+		Convey("is restored when closed", func() {
+			o.SetCursorStyle(CursorStyleSteadyBar)
+			o.Close()
+			style, color = o.GetCursorStyle()
+			So(style, ShouldEqual, CursorStyleDefault)
+			So(color, ShouldEqual, ColorDefault)
+		})
+	})
+}
+
+func TestDisplayManagerCursor(t *testing.T) {
+	Convey("Display manager cursor ownership", t, func() {
+		d := NewDisplayManager("cursor", OffscreenDisplayTtyPath)
+		defer d.Destroy()
+		d.CaptureDisplay(OffscreenDisplayTtyPath)
+		o, ok := d.Display().(OffscreenDisplay)
+		So(ok, ShouldBeTrue)
+		wa := NewWindow("a", nil)
+		wb := NewWindow("b", nil)
+		d.AddWindow(wa)
+		d.AddWindow(wb)
+		d.SetActiveWindow(wa)
+		So(d.GetCursorOwner(), ShouldEqual, wa)
+		So(d.GetCursor(wa), ShouldResemble, HiddenCursor)
+
+		d.SetCursor(wa, MakeCursor(1, 1, CursorStyleSteadyBar))
+		d.SetCursor(wb, Cursor{X: 3, Y: 2, Visible: true, Style: CursorStyleBlinkingBlock, Color: ColorRed})
+		x, y, visible := o.GetCursor()
+		So(x, ShouldEqual, 1)
+		So(y, ShouldEqual, 1)
+		So(visible, ShouldBeTrue)
+		style, _ := o.GetCursorStyle()
+		So(style, ShouldEqual, CursorStyleSteadyBar)
+
+		Convey("the active window owns the cursor by default", func() {
+			d.SetActiveWindow(wb)
+			So(d.GetCursorOwner(), ShouldEqual, wb)
+			d.SetCursorOwner(nil)
+			x, y, _ = o.GetCursor()
+			So(x, ShouldEqual, 3)
+			So(y, ShouldEqual, 2)
+			style, color := o.GetCursorStyle()
+			So(style, ShouldEqual, CursorStyleBlinkingBlock)
+			So(color, ShouldEqual, ColorRed)
+		})
+
+		Convey("ownership can be given to another window", func() {
+			d.SetCursorOwner(wb)
+			So(d.GetCursorOwner(), ShouldEqual, wb)
+			So(d.ActiveWindow(), ShouldEqual, wa)
+			style, _ := o.GetCursorStyle()
+			So(style, ShouldEqual, CursorStyleBlinkingBlock)
+			// changes by other windows are not displayed
+			d.SetCursor(wa, MakeCursor(0, 0, CursorStyleSteadyUnderline))
+			style, _ = o.GetCursorStyle()
+			So(style, ShouldEqual, CursorStyleBlinkingBlock)
+			d.SetCursor(wb, HiddenCursor)
+			_, _, visible = o.GetCursor()
+			So(visible, ShouldBeFalse)
+			style, _ = o.GetCursorStyle()
+			So(style, ShouldEqual, CursorStyleDefault)
+		})
+
+		Convey("the user's cursor style is restored on release", func() {
+			d.ReleaseDisplay()
+			style, color := o.GetCursorStyle()
+			So(style, ShouldEqual, CursorStyleDefault)
+			So(color, ShouldEqual, ColorDefault)
+		})
+	})
+}
@@ -75,6 +75,41 @@ type Display interface {
 	// ShowCursor(-1, -1).
 	HideCursor()
 
+	// SetCursorStyle sets the shape of the cursor and whether it blinks,
+	// if the terminal supports it.  CursorStyleDefault restores the style
+	// the user has configured for their terminal, which is also done when
+	// the display is closed.
+	SetCursorStyle(style CursorStyle)
+
+	// SetCursorColor sets the color of the cursor, if the terminal supports
+	// it.  ColorDefault, or any other color without an RGB value, restores
+	// the default cursor color.
+	SetCursorColor(color Color)
+
+	// SetSyncOutput overrides whether the output of each frame is wrapped
+	// in synchronized update sequences, to avoid tearing.  By default this
+	// is SyncOutputAuto, which synchronizes output if the terminal reports
+	// support for it.
+	SetSyncOutput(mode SyncOutputMode)
+
+	// HasSyncOutput returns true if the output of each frame is wrapped in
+	// synchronized update sequences.
+	HasSyncOutput() bool
+
+	// SetClipboard sets the given clipboard selection to the text given,
+	// if the terminal supports it.
+	SetClipboard(selection ClipboardSelection, text string) error
+
+	// RequestClipboard asks for the contents of the given clipboard
+	// selection, which are posted as an EventClipboard if the terminal
+	// supports and permits reading the clipboard.
+	RequestClipboard(selection ClipboardSelection) error
+
+	// SetTitle sets the window title and icon name of the terminal, if
+	// supported.  The title the user had before is restored when the
+	// display is closed, where the terminal permits.
+	SetTitle(title string)
+
 	// Size returns the display size as width, height.  This changes in
 	// response to a call to Clear or Flush.
 	Size() (w, h int)
@@ -103,6 +138,25 @@ type Display interface {
 	// DisablePaste() disables bracketed paste mode.
 	DisablePaste()
 
+	// EnableFocus enables reporting when the terminal gains or loses
+	// focus, with an EventFocus, if supported.
+	EnableFocus()
+
+	// DisableFocus disables reporting focus changes.
+	DisableFocus()
+
+	// EnableKittyKeyboard enables the given enhancements of the kitty
+	// keyboard protocol, if the terminal supports it, see EventKey.  Keys
+	// are reported as usual by terminals that do not.
+	EnableKittyKeyboard(flags KittyKeyboardFlags)
+
+	// DisableKittyKeyboard disables the kitty keyboard protocol.
+	DisableKittyKeyboard()
+
+	// HasKittyKeyboard returns true if keys are reported using the kitty
+	// keyboard protocol.
+	HasKittyKeyboard() bool
+
 	// HasMouse returns true if the terminal (apparently) supports a
 	// mouse.  Note that the a return value of true doesn't guarantee that
 	// a mouse/pointing device is present; a false return definitely
@@ -131,6 +185,15 @@ type Display interface {
 	// or during a resize event.
 	Sync()
 
+	// Suspend hands the terminal back to the user's shell, restoring the
+	// terminal modes the display started with, leaving the alternate screen
+	// and turning off mouse reporting.  Nothing is shown until resumed.
+	Suspend() error
+
+	// Resume takes the terminal back from the user's shell and redraws
+	// the whole display, undoing Suspend.
+	Resume() error
+
 	// CharacterSet returns information about the character set.
 	// This isn't the full locale, but it does give us the input/output
 	// character set.  Note that this is just for diagnostic purposes,
@@ -214,6 +277,16 @@ func NewDisplay() (Display, error) {
 	}
 }
 
+// NewDisplayWithTtyPath returns a Display for the terminal device found at
+// the given ttyPath, for example "/dev/pts/7" or the slave side of a pty
+// allocated by the caller.  An empty ttyPath is the same as NewDisplay.
+func NewDisplayWithTtyPath(ttyPath string) (Display, error) {
+	if ttyPath == "" {
+		return NewDisplay()
+	}
+	return NewTerminfoDisplayWithTtyPath(ttyPath)
+}
+
 // MouseFlags are options to modify the handling of mouse events.
 // Actual events can be or'd together.
 type MouseFlags int
@@ -16,6 +16,12 @@ package cdk
 
 import (
 	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -30,6 +36,7 @@ import (
 var (
 	DisplayCallQueueCapacity = 16
 	cdkDisplayManager        DisplayManager
+	cdkDisplayManagerLock    = &sync.RWMutex{}
 )
 
 const (
@@ -42,6 +49,10 @@ const (
 	SignalEventKey        Signal   = "event-key"
 	SignalEventMouse      Signal   = "event-mouse"
 	SignalEventResize     Signal   = "event-resize"
+	SignalEventClipboard  Signal   = "event-clipboard"
+	SignalEventPaste      Signal   = "event-paste"
+	SignalEventFocus      Signal   = "event-focus"
+	SignalDisplayTitle    Signal   = "display-title"
 )
 
 func init() {
@@ -63,6 +74,8 @@ type DisplayManager interface {
 	DisplayCaptured() bool
 	CaptureDisplay(ttyPath string)
 	ReleaseDisplay()
+	SetInlineMode(inline bool, height int)
+	IsInlineMode() bool
 	IsMonochrome() bool
 	Colors() (numberOfColors int)
 
@@ -75,11 +88,49 @@ type DisplayManager interface {
 	SetActiveWindow(w Window)
 	AddWindow(w Window) int
 	GetWindows() []Window
+	RemoveWindow(w Window)
+	GetWindowStack() []Window
+	RaiseWindow(w Window)
+	LowerWindow(w Window)
+	GetWindowRegion(w Window) Region
+	SetWindowRegion(w Window, region Region)
+	MoveWindow(w Window, x, y int)
+
+	PushModal(w Window)
+	CloseModal(w Window, result interface{})
+	GetModal() Window
+	AwaitModal(w Window) (result interface{}, err error)
 
 	App() *CApp
 	ProcessEvent(evt Event) EventFlag
 	DrawScreen() EventFlag
 
+	Clipboard() Clipboard
+
+	GetKeyMap() KeyMap
+	SetKeyMap(keyMap KeyMap)
+	SetChordTimeout(timeout time.Duration)
+	GetChordTimeout() time.Duration
+
+	SetPasteBuffering(buffer bool, limit int)
+	GetPasteBuffering() (buffer bool, limit int)
+
+	SetClickTimeout(timeout time.Duration)
+	GetClickTimeout() time.Duration
+	SetClickTolerance(cells int)
+	GetClickTolerance() int
+
+	GrabPointer(owner Object)
+	ReleasePointer()
+	GetPointerGrab() Object
+	IsPointerGrabbed() bool
+
+	Suspend() error
+	Resume() error
+	IsSuspended() bool
+	RunExternal(cmd *exec.Cmd) error
+
+	Invalidate(region Region)
 	RequestDraw()
 	RequestShow()
 	RequestSync()
@@ -88,6 +139,25 @@ type DisplayManager interface {
 	AsyncCall(fn DisplayCallbackFn) error
 	AwaitCall(fn DisplayCallbackFn) error
 
+	AddTimeout(delay time.Duration, fn TimerCallbackFn) (id int)
+	CancelTimeout(id int) bool
+
+	EventQueueMetrics() EventQueueMetrics
+
+	SetFrameRate(fps int)
+	GetFrameRate() (fps int)
+	SetRenderDirtyOnly(dirtyOnly bool)
+	IsRenderDirtyOnly() bool
+	LastFrameStats() FrameStats
+
+	SetCursor(w Window, cursor Cursor)
+	GetCursor(w Window) Cursor
+	SetCursorOwner(w Window)
+	GetCursorOwner() Window
+
+	Screenshot() Screenshot
+	SaveScreenshot(path string) error
+
 	IsRunning() bool
 	Run() error
 }
@@ -100,21 +170,94 @@ type CDisplayManager struct {
 
 	captureCtrlC bool
 
-	active  int
-	windows []Window
-	wCanvas []Canvas
+	active   int
+	windows  []Window
+	wCanvas  []Canvas
+	wRegion  []*Region
+	wStack   []Window
+	screen   *CCanvas
+	rendered Window
 
 	app      *CApp
+	initFn   DisplayInitFn
+	timers   *timers
+	recordTo io.Writer
+
 	ttyPath  string
 	display  Display
 	captured bool
 
-	running  bool
+	inline       bool
+	inlineHeight int
+
+	screenshotPath  string
+	screenshotCount int
+
+	clipboard Clipboard
+
+	shownTitle   string
+	titleChanged bool
+	titleLock    *sync.Mutex
+
+	keyMap       KeyMap
+	chord        KeyBinding
+	chordAt      time.Time
+	chordTimeout time.Duration
+	chordLock    *sync.Mutex
+
+	pasteBuffer   bool
+	pasteLimit    int
+	pasteStart    *EventPaste
+	pasteKeys     []Event
+	pasteText     strings.Builder
+	pasteOverflow bool
+	pasteCR       bool
+	pasteLock     *sync.Mutex
+
+	clickCount     int
+	clickAt        time.Time
+	clickX         int
+	clickY         int
+	clickButton    ButtonMask
+	clickTimeout   time.Duration
+	clickTolerance int
+	clickLock      *sync.Mutex
+
+	grabOwner  Object
+	grabEnding bool
+	grabLock   *sync.Mutex
+
+	modals    []*modalEntry
+	modalLock *sync.Mutex
+
+	suspended   bool
+	external    chan struct{}
+	suspendLock *sync.Mutex
+
+	cursors     map[int]Cursor
+	cursorOwner Window
+	cursorLock  *sync.Mutex
+
+	frameRate  int
+	dirtyOnly  bool
+	drawNext   bool
+	frameCount uint64
+	frameStats FrameStats
+	frameLock  *sync.Mutex
+
+	// running is read atomically and changed with the quitLock held
+	quitPending bool
+	running     int32
+	stopped     bool
+	quitLock    *sync.Mutex
+
 	waiting  bool
+	workers  *sync.WaitGroup
+	stopping chan struct{}
 	done     chan bool
 	queue    chan DisplayCallbackFn
 	events   chan Event
-	process  chan Event
+	process  EventQueue
 	requests chan ScreenStateReq
 }
 
@@ -126,62 +269,112 @@ func NewDisplayManager(title string, ttyPath string) *CDisplayManager {
 	return d
 }
 
+// GetDisplayManager returns the default display manager, see
+// GetDefaultDisplayManager.
 func GetDisplayManager() (dm DisplayManager) {
+	return GetDefaultDisplayManager()
+}
+
+// GetDefaultDisplayManager returns the display manager that package-level
+// functions, such as GetCurrentTheme and AddTimeout, act upon.  Unless set
+// with SetDefaultDisplayManager, this is the first display manager made that
+// has not yet been destroyed.  Returns nil if there is no default.
+func GetDefaultDisplayManager() (dm DisplayManager) {
+	cdkDisplayManagerLock.RLock()
+	defer cdkDisplayManagerLock.RUnlock()
 	dm = cdkDisplayManager
 	return
 }
 
+// SetDefaultDisplayManager makes the given display manager the default,
+// passing nil clears the default.  When the default display manager is
+// destroyed, there is no default until another display manager is made or
+// SetDefaultDisplayManager is called again.
+func SetDefaultDisplayManager(dm DisplayManager) {
+	cdkDisplayManagerLock.Lock()
+	defer cdkDisplayManagerLock.Unlock()
+	cdkDisplayManager = dm
+}
+
 func GetCurrentTheme() (theme Theme) {
 	theme = DefaultColorTheme
-	if cdkDisplayManager != nil {
-		theme = cdkDisplayManager.GetTheme()
+	if dm := GetDefaultDisplayManager(); dm != nil {
+		theme = dm.GetTheme()
 	}
 	return
 }
 
 func SetCurrentTheme(theme Theme) {
-	if cdkDisplayManager != nil {
-		cdkDisplayManager.SetTheme(theme)
+	if dm := GetDefaultDisplayManager(); dm != nil {
+		dm.SetTheme(theme)
 	}
 }
 
 // Initialization
 func (d *CDisplayManager) Init() (already bool) {
-	check := TypesManager.GetTypeItems(TypeDisplayManager)
-	if len(check) > 0 {
-		FatalF("only one display permitted at a time")
-	}
 	if d.InitTypeItem(TypeDisplayManager) {
 		return true
 	}
 	d.CObject.Init()
 
 	d.captured = false
-	d.running = false
 	d.waiting = true
+	d.workers = &sync.WaitGroup{}
+	d.stopping = make(chan struct{})
 	d.done = make(chan bool)
 	d.queue = make(chan DisplayCallbackFn, DisplayCallQueueCapacity)
 	d.events = make(chan Event, DisplayCallQueueCapacity)
-	d.process = make(chan Event, DisplayCallQueueCapacity)
+	d.process = NewEventQueue(EventQueueCapacity)
 	d.requests = make(chan ScreenStateReq, DisplayCallQueueCapacity)
 
 	d.windows = []Window{}
 	d.active = -1
+	d.cursors = make(map[int]Cursor)
+	d.cursorLock = &sync.Mutex{}
+	d.titleLock = &sync.Mutex{}
+	d.keyMap = NewKeyMap()
+	d.chordTimeout = DefaultChordTimeout
+	d.chordLock = &sync.Mutex{}
+	d.frameRate = DefaultFrameRate
+	d.dirtyOnly = true
+	d.frameLock = &sync.Mutex{}
+	d.timers = newTimers()
+	d.clipboard = NewClipboard(d)
+	d.pasteBuffer = false
+	d.pasteLimit = DefaultPasteLimit
+	d.pasteLock = &sync.Mutex{}
+	d.clickTimeout = DefaultClickTimeout
+	d.clickTolerance = DefaultClickTolerance
+	d.clickLock = &sync.Mutex{}
+	d.grabLock = &sync.Mutex{}
+	d.modalLock = &sync.Mutex{}
+	d.suspendLock = &sync.Mutex{}
+	d.quitLock = &sync.Mutex{}
 	d.SetTheme(DefaultColorTheme)
 
-	cdkDisplayManager = d
+	cdkDisplayManagerLock.Lock()
+	if cdkDisplayManager == nil {
+		cdkDisplayManager = d
+	}
+	cdkDisplayManagerLock.Unlock()
 	d.Emit(SignalDisplayInit, d)
 	return false
 }
 
 func (d *CDisplayManager) Destroy() {
+	d.stop()
+	d.Lock()
 	if d.display != nil {
 		d.display.Close()
 	}
-	close(d.done)
-	close(d.queue)
-	close(d.process)
-	close(d.requests)
+	d.Unlock()
+	d.process.Close()
+	d.timers.StopAll()
+	cdkDisplayManagerLock.Lock()
+	if cdkDisplayManager == d {
+		cdkDisplayManager = nil
+	}
+	cdkDisplayManagerLock.Unlock()
 	d.CObject.Destroy()
 }
 
@@ -189,8 +382,49 @@ func (d *CDisplayManager) GetTitle() string {
 	return d.title
 }
 
+// SetTitle sets the title of the display manager, which is shown as the
+// title of the terminal while the active window has no title of its own.
 func (d *CDisplayManager) SetTitle(title string) {
+	defer d.emitTitle()
+	d.Lock()
+	defer d.Unlock()
 	d.title = title
+	d.applyTitle()
+}
+
+// applyTitle shows the title of the active window, or of the display
+// manager, upon the display if changed since last shown.  The change is
+// signalled by emitTitle, once the display manager is unlocked.
+func (d *CDisplayManager) applyTitle() {
+	if d.display == nil {
+		return
+	}
+	title := d.title
+	if w := d.activeWindow(); w != nil && w.GetTitle() != "" {
+		title = w.GetTitle()
+	}
+	d.titleLock.Lock()
+	if title == d.shownTitle {
+		d.titleLock.Unlock()
+		return
+	}
+	d.shownTitle = title
+	d.titleChanged = true
+	d.titleLock.Unlock()
+	d.display.SetTitle(title)
+}
+
+// emitTitle emits SignalDisplayTitle if the title shown has changed since
+// last emitted, it must not be called with the display manager locked so
+// that listeners are free to call upon it
+func (d *CDisplayManager) emitTitle() {
+	d.titleLock.Lock()
+	title, changed := d.shownTitle, d.titleChanged
+	d.titleChanged = false
+	d.titleLock.Unlock()
+	if changed {
+		d.Emit(SignalDisplayTitle, d, title)
+	}
 }
 
 func (d *CDisplayManager) GetTtyPath() string {
@@ -210,32 +444,123 @@ func (d *CDisplayManager) DisplayCaptured() bool {
 }
 
 func (d *CDisplayManager) CaptureDisplay(ttyPath string) {
-	d.Lock()
-	defer d.Unlock()
 	var err error
+	var display Display
 	if ttyPath == OffscreenDisplayTtyPath {
-		if d.display, err = MakeOffscreenDisplay(""); err != nil {
+		if display, err = MakeOffscreenDisplay(""); err != nil {
 			FatalF("error getting offscreen display: %v", err)
 		}
+	} else if d.inline {
+		if display, err = NewInlineDisplay(ttyPath, d.inlineHeight); err != nil {
+			FatalF("error getting new inline display: %v", err)
+		}
+		if err = display.Init(); err != nil {
+			FatalF("error initializing new inline display: %v", err)
+		}
 	} else {
-		if d.display, err = NewDisplay(); err != nil {
+		if display, err = NewDisplayWithTtyPath(ttyPath); err != nil {
 			FatalF("error getting new display: %v", err)
 		}
-		if err = d.display.Init(); err != nil {
+		if err = display.Init(); err != nil {
 			FatalF("error initializing new display: %v", err)
 		}
 	}
+	d.captureDisplay(display)
+}
+
+// SetInlineMode makes the next display captured render inline, below the
+// cursor, with the given height instead of taking over the whole terminal.
+// See NewInlineDisplay for details.
+func (d *CDisplayManager) SetInlineMode(inline bool, height int) {
+	d.Lock()
+	defer d.Unlock()
+	d.inline = inline
+	d.inlineHeight = height
+}
+
+func (d *CDisplayManager) IsInlineMode() bool {
+	d.Lock()
+	defer d.Unlock()
+	return d.inline
+}
+
+// captureDisplay takes ownership of the given display, which must already be
+// initialized, and prepares it for use
+func (d *CDisplayManager) captureDisplay(display Display) {
+	defer d.emitTitle()
+	d.Lock()
+	defer d.Unlock()
+	if d.recordTo != nil {
+		display = NewRecordingDisplay(display, d.title, d.recordTo)
+	}
+	d.display = display
+	d.rendered = nil
 	defStyle := StyleDefault.
 		Background(ColorReset).
 		Foreground(ColorReset)
 	d.display.SetStyle(defStyle)
 	d.display.EnableMouse()
 	d.display.EnablePaste()
+	d.display.EnableFocus()
 	d.display.Clear()
 	d.captured = true
+	d.applyTitle()
 	d.Emit(SignalDisplayCaptured, d)
 }
 
+// RecordTo makes the display manager record the session to the given
+// writer, in asciicast v2 format, from the next time a display is captured.
+// Passing nil stops recording the next time a display is captured.
+func (d *CDisplayManager) RecordTo(w io.Writer) {
+	d.Lock()
+	defer d.Unlock()
+	d.recordTo = w
+}
+
+// Screenshot returns a copy of what is currently on the display, or nil if
+// there is no display captured.
+func (d *CDisplayManager) Screenshot() Screenshot {
+	d.Lock()
+	defer d.Unlock()
+	if d.display == nil {
+		return nil
+	}
+	return TakeScreenshot(d.display)
+}
+
+// SaveScreenshot saves what is currently on the display to the given path,
+// the format of the screenshot is determined by the file extension.
+func (d *CDisplayManager) SaveScreenshot(path string) error {
+	s := d.Screenshot()
+	if s == nil {
+		return fmt.Errorf("display not captured or otherwise missing")
+	}
+	return s.Save(path)
+}
+
+// SetScreenshotPath enables saving a screenshot each time the ScreenshotKey
+// is pressed.  The first screenshot is saved to the path given and those
+// following have a number added before the file extension.  An empty path
+// disables the ScreenshotKey.
+func (d *CDisplayManager) SetScreenshotPath(path string) {
+	d.Lock()
+	defer d.Unlock()
+	d.screenshotPath = path
+	d.screenshotCount = 0
+}
+
+func (d *CDisplayManager) nextScreenshotPath() (path string) {
+	d.Lock()
+	defer d.Unlock()
+	d.screenshotCount++
+	path = d.screenshotPath
+	if d.screenshotCount > 1 {
+		ext := filepath.Ext(path)
+		path = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), d.screenshotCount, ext)
+	}
+	return
+}
+
 func (d *CDisplayManager) ReleaseDisplay() {
 	d.Lock()
 	defer d.Unlock()
@@ -243,7 +568,11 @@ func (d *CDisplayManager) ReleaseDisplay() {
 		d.display.Close()
 		d.display = nil
 	}
+	d.rendered = nil
 	d.captured = false
+	d.titleLock.Lock()
+	d.shownTitle = ""
+	d.titleLock.Unlock()
 }
 
 func (d *CDisplayManager) IsMonochrome() bool {
@@ -280,6 +609,13 @@ func (d *CDisplayManager) DefaultTheme() Theme {
 }
 
 func (d *CDisplayManager) ActiveWindow() Window {
+	d.Lock()
+	defer d.Unlock()
+	return d.activeWindow()
+}
+
+// activeWindow returns the active window, the display manager must be locked
+func (d *CDisplayManager) activeWindow() Window {
 	if len(d.windows) > d.active && d.active >= 0 {
 		return d.windows[d.active]
 	}
@@ -301,35 +637,32 @@ func (d *CDisplayManager) windowIndex(w Window) (index int) {
 	return
 }
 
+// SetActiveWindow makes the window given, added if need be, the window that
+// keyboard events are sent to, and raises it above the other windows of its
+// type.
 func (d *CDisplayManager) SetActiveWindow(w Window) {
 	d.Lock()
-	id := -1
-	var window Window
-	for id, window = range d.windows {
-		if window == w {
-			break
-		}
-	}
-	if id > -1 {
+	if id := d.windowIndex(w); id > -1 {
+		changed := id != d.active
 		d.active = id
 		d.Unlock()
+		d.RaiseWindow(w)
+		if changed {
+			d.breakPointerGrab()
+		}
 		return
 	}
 	d.Unlock()
-	d.active = d.AddWindow(w)
+	id := d.AddWindow(w)
+	d.Lock()
+	d.active = id
+	d.Unlock()
 }
 
 func (d *CDisplayManager) AddWindow(w Window) int {
 	d.Lock()
-	defer d.Unlock()
-	id := -1
-	var window Window
-	for id, window = range d.windows {
-		if window == w {
-			break
-		}
-	}
-	if id > -1 {
+	if id := d.windowIndex(w); id > -1 {
+		d.Unlock()
 		d.LogError("display has window already: %v", w)
 		return id
 	}
@@ -340,18 +673,44 @@ func (d *CDisplayManager) AddWindow(w Window) int {
 	d.wCanvas = append(d.wCanvas, NewCanvas(Point2I{}, size, d.GetTheme().Content.Normal))
 	w.SetDisplayManager(d)
 	d.windows = append(d.windows, w)
-	return len(d.windows) - 1
+	d.wRegion = append(d.wRegion, nil)
+	d.wStack = append(d.wStack, w)
+	id := len(d.windows) - 1
+	d.Unlock()
+	// the new window may cover the owner of a pointer grab
+	d.breakPointerGrab()
+	return id
 }
 
 func (d *CDisplayManager) GetWindows() []Window {
 	return d.windows
 }
 
+// Clipboard returns the clipboard of the user's system, read and written
+// through the display.
+func (d *CDisplayManager) Clipboard() Clipboard {
+	return d.clipboard
+}
+
 func (d *CDisplayManager) App() *CApp {
 	return d.app
 }
 
 func (d *CDisplayManager) ProcessEvent(evt Event) EventFlag {
+	flag := EVENT_PASS
+	for _, pe := range d.bufferPaste(evt) {
+		for _, e := range d.synthesizeClicks(pe) {
+			if f := d.processEvent(e); f == EVENT_STOP {
+				flag = EVENT_STOP
+			}
+		}
+		// after the owner is sent the click of the button let go, if any
+		d.endReleasedPointerGrab()
+	}
+	return flag
+}
+
+func (d *CDisplayManager) processEvent(evt Event) EventFlag {
 	switch e := evt.(type) {
 	case *EventError:
 		d.LogErr(e)
@@ -362,7 +721,18 @@ func (d *CDisplayManager) ProcessEvent(evt Event) EventFlag {
 		}
 		return d.Emit(SignalEventError, d, e)
 	case *EventKey:
-		if d.captureCtrlC {
+		// releases and repeats, as reported with the kitty keyboard
+		// protocol, do not screenshot, quit or suspend again
+		pressed := e.EventType() == KeyEventPress
+		if d.screenshotPath != "" && e.Key() == ScreenshotKey {
+			if pressed {
+				if err := d.SaveScreenshot(d.nextScreenshotPath()); err != nil {
+					d.LogErr(err)
+				}
+			}
+			return EVENT_STOP
+		}
+		if d.captureCtrlC && pressed {
 			switch e.Key() {
 			case KeyCtrlC:
 				d.LogTrace("display captured CtrlC")
@@ -372,34 +742,66 @@ func (d *CDisplayManager) ProcessEvent(evt Event) EventFlag {
 				d.RequestQuit()
 			}
 		}
-		if w := d.ActiveWindow(); w != nil {
+		if f := d.processAccel(d.inputWindow(), e); f == EVENT_STOP {
+			return EVENT_STOP
+		}
+		if w := d.inputWindow(); w != nil {
 			if f := w.ProcessEvent(evt); f == EVENT_STOP {
 				return EVENT_STOP
 			}
 		}
-		return d.Emit(SignalEventKey, d, e)
+		if f := d.Emit(SignalEventKey, d, e); f == EVENT_STOP {
+			return EVENT_STOP
+		}
+		if pressed && e.Key() == KeyCtrlZ && d.jobControl() != nil {
+			// the terminal is in raw mode, Ctrl+Z does not send SIGTSTP
+			return d.processSuspend(NewEventSuspend(true))
+		}
+		return EVENT_PASS
 	case *EventMouse:
-		if w := d.ActiveWindow(); w != nil {
-			if f := w.ProcessEvent(evt); f == EVENT_STOP {
-				return EVENT_STOP
-			}
+		if f, grabbed := d.processGrabbedMouse(e); grabbed {
+			return f
+		}
+		if f := d.processWindowMouse(e); f == EVENT_STOP {
+			return EVENT_STOP
 		}
 		return d.Emit(SignalEventMouse, d, e)
 	case *EventResize:
+		d.Lock()
+		d.layoutWindows()
+		d.Unlock()
 		if aw := d.ActiveWindow(); aw != nil {
-			wid := d.windowIndex(aw)
-			w, h := d.display.Size()
-			alloc := MakeRectangle(w, h)
-			if d.wCanvas[wid] != nil {
-				d.wCanvas[wid].Resize(alloc, d.GetTheme().Content.Normal)
-				if f := aw.ProcessEvent(evt); f == EVENT_STOP {
-					return EVENT_STOP
-				}
-			} else {
-				d.LogError("missing canvas for wid: %v", wid)
+			if f := aw.ProcessEvent(evt); f == EVENT_STOP {
+				return EVENT_STOP
 			}
 		}
 		return d.Emit(SignalEventResize, d, e)
+	case *EventClipboard:
+		if w := d.ActiveWindow(); w != nil {
+			if f := w.ProcessEvent(evt); f == EVENT_STOP {
+				return EVENT_STOP
+			}
+		}
+		return d.Emit(SignalEventClipboard, d, e)
+	case *EventPaste:
+		if w := d.inputWindow(); w != nil {
+			if f := w.ProcessEvent(evt); f == EVENT_STOP {
+				return EVENT_STOP
+			}
+		}
+		return d.Emit(SignalEventPaste, d, e)
+	case *EventFocus:
+		if !e.Focused() {
+			d.breakPointerGrab()
+		}
+		if w := d.ActiveWindow(); w != nil {
+			if f := w.ProcessEvent(evt); f == EVENT_STOP {
+				return EVENT_STOP
+			}
+		}
+		return d.Emit(SignalEventFocus, d, e)
+	case *EventSuspend:
+		return d.processSuspend(e)
 	}
 	if w := d.ActiveWindow(); w != nil {
 		if f := w.ProcessEvent(evt); f == EVENT_STOP {
@@ -410,6 +812,7 @@ func (d *CDisplayManager) ProcessEvent(evt Event) EventFlag {
 }
 
 func (d *CDisplayManager) DrawScreen() EventFlag {
+	defer d.emitTitle()
 	d.Lock()
 	defer d.Unlock()
 	if !d.captured || d.display == nil {
@@ -417,152 +820,392 @@ func (d *CDisplayManager) DrawScreen() EventFlag {
 		return EVENT_PASS
 	}
 	var window Window
-	if window = d.ActiveWindow(); window == nil {
+	if window = d.activeWindow(); window == nil {
 		d.LogDebug("cannot draw the display, display missing a window")
 		return EVENT_PASS
 	}
-	wid := d.windowIndex(window)
-	if len(d.wCanvas) < wid {
-		d.LogError("missing canvas for window: %v", window.ObjectName())
+	d.applyTitle()
+	d.layoutWindows()
+	// the windows are composited upon the screen from the bottom up, the
+	// cells a window does not draw upon show those beneath it
+	style := d.GetTheme().Content.Normal
+	size := MakeRectangle(d.display.Size())
+	if d.screen == nil {
+		d.screen = NewCanvas(Point2I{}, size, style)
+	} else if current := d.screen.GetSize(); current.W != size.W || current.H != size.H {
+		d.screen.Resize(size, style)
+	}
+	for x := 0; x < size.W; x++ {
+		for y := 0; y < size.H; y++ {
+			_ = d.screen.SetRune(x, y, ' ', style)
+		}
+	}
+	drawn := false
+	for _, sw := range d.windowStack() {
+		canvas := d.wCanvas[d.windowIndex(sw)]
+		if f := sw.Draw(canvas); f == EVENT_STOP {
+			drawn = true
+			if err := d.screen.Composite(canvas); err != nil {
+				d.LogErr(err)
+			}
+		}
+	}
+	if !drawn {
 		return EVENT_PASS
 	}
-	if f := window.Draw(d.wCanvas[wid]); f == EVENT_STOP {
-		if err := d.wCanvas[wid].Render(d.display); err != nil {
-			d.LogErr(err)
+	if d.rendered != window {
+		// the display has another window, or nothing, on it
+		d.screen.InvalidateAll()
+		d.rendered = window
+	}
+	if err := d.screen.Render(d.display); err != nil {
+		d.LogErr(err)
+	}
+	d.applyCursor()
+	return EVENT_STOP
+}
+
+// SetCursor sets the cursor the given window wants displayed.  Only the
+// cursor of the window that owns the cursor is displayed, see
+// SetCursorOwner.  Windows that have not set a cursor have it hidden.
+func (d *CDisplayManager) SetCursor(w Window, cursor Cursor) {
+	if w == nil {
+		return
+	}
+	d.cursorLock.Lock()
+	d.cursors[w.ObjectID()] = cursor
+	d.cursorLock.Unlock()
+	d.Lock()
+	d.applyCursor()
+	d.Unlock()
+}
+
+// GetCursor returns the cursor the given window wants displayed.
+func (d *CDisplayManager) GetCursor(w Window) Cursor {
+	d.cursorLock.Lock()
+	defer d.cursorLock.Unlock()
+	if w != nil {
+		if cursor, ok := d.cursors[w.ObjectID()]; ok {
+			return cursor
 		}
-		return EVENT_STOP
 	}
-	return EVENT_PASS
+	return HiddenCursor
+}
+
+// SetCursorOwner gives the given window ownership of the cursor, passing nil
+// makes the active window the owner, which is the default.
+func (d *CDisplayManager) SetCursorOwner(w Window) {
+	d.cursorLock.Lock()
+	d.cursorOwner = w
+	d.cursorLock.Unlock()
+	d.Lock()
+	d.applyCursor()
+	d.Unlock()
+}
+
+// GetCursorOwner returns the window that owns the cursor.
+func (d *CDisplayManager) GetCursorOwner() Window {
+	d.Lock()
+	defer d.Unlock()
+	return d.cursorOwnerWindow()
+}
+
+// cursorOwnerWindow returns the window that owns the cursor, the display
+// manager must be locked
+func (d *CDisplayManager) cursorOwnerWindow() Window {
+	d.cursorLock.Lock()
+	owner := d.cursorOwner
+	d.cursorLock.Unlock()
+	if owner != nil && d.windowIndex(owner) > -1 {
+		return owner
+	}
+	return d.activeWindow()
+}
+
+// applyCursor updates the display with the cursor of the window that owns
+// it, to be shown with the next call to Show.  The display manager must be
+// locked.
+func (d *CDisplayManager) applyCursor() {
+	display := d.display
+	if display == nil {
+		return
+	}
+	owner := d.cursorOwnerWindow()
+	cursor := d.GetCursor(owner)
+	if cursor.Visible {
+		// the cursor is positioned within the region of its window
+		origin := Point2I{}
+		if owner != nil {
+			origin = d.windowRegion(d.windowIndex(owner)).Origin()
+		}
+		display.ShowCursor(origin.X+cursor.X, origin.Y+cursor.Y)
+	} else {
+		display.HideCursor()
+	}
+	display.SetCursorStyle(cursor.Style)
+	display.SetCursorColor(cursor.Color)
+}
+
+// Invalidate marks the given region of the display as needing to be drawn
+// again, even if unchanged, and requests a draw
+func (d *CDisplayManager) Invalidate(region Region) {
+	d.Lock()
+	if d.screen != nil {
+		d.screen.Invalidate(region)
+	}
+	d.Unlock()
+	if d.IsRunning() {
+		d.RequestDraw()
+	}
 }
 
 func (d *CDisplayManager) RequestDraw() {
-	if d.running {
-		d.requests <- DrawRequest
+	if d.IsRunning() {
+		d.request(DrawRequest)
 	} else {
 		TraceF("application not running")
 	}
 }
 
 func (d *CDisplayManager) RequestShow() {
-	if d.running {
-		d.requests <- ShowRequest
+	if d.IsRunning() {
+		d.request(ShowRequest)
 	} else {
 		TraceF("application not running")
 	}
 }
 
 func (d *CDisplayManager) RequestSync() {
-	if d.running {
-		d.requests <- SyncRequest
+	if d.IsRunning() {
+		d.request(SyncRequest)
 	} else {
 		TraceF("application not running")
 	}
 }
 
+// request sends the given request to the screen request worker, giving up
+// once the display manager is stopping
+func (d *CDisplayManager) request(request ScreenStateReq) {
+	select {
+	case d.requests <- request:
+	case <-d.stopping:
+	}
+}
+
+// RequestQuit asks the display manager to stop running.  When not yet
+// running, the request is kept and Run stops as soon as it has started.
 func (d *CDisplayManager) RequestQuit() {
-	if d.running {
-		d.requests <- QuitRequest
-	} else {
-		TraceF("application not running")
+	d.quitLock.Lock()
+	if !d.IsRunning() {
+		d.quitPending = true
+		d.quitLock.Unlock()
+		TraceF("application not running, quitting once started")
+		return
 	}
+	d.quitLock.Unlock()
+	d.request(QuitRequest)
 }
 
 func (d *CDisplayManager) AsyncCall(fn DisplayCallbackFn) error {
-	if !d.running {
+	if !d.IsRunning() {
 		return fmt.Errorf("application not running")
 	}
-	d.queue <- fn
+	select {
+	case d.queue <- fn:
+	case <-d.stopping:
+		return fmt.Errorf("application stopped")
+	}
 	return nil
 }
 
 func (d *CDisplayManager) AwaitCall(fn DisplayCallbackFn) error {
-	if !d.running {
+	if !d.IsRunning() {
 		return fmt.Errorf("application not running")
 	}
-	var err error
-	done := make(chan bool)
-	d.queue <- func(d DisplayManager) error {
-		err = fn(d)
-		done <- true
+	// buffered so that the call never waits upon a caller given up
+	done := make(chan error, 1)
+	select {
+	case d.queue <- func(d DisplayManager) error {
+		done <- fn(d)
 		return nil
+	}:
+	case <-d.stopping:
+		return fmt.Errorf("application stopped")
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-d.stopping:
+		return fmt.Errorf("application stopped")
 	}
-	<-done
-	return err
 }
 
 func (d *CDisplayManager) PostEvent(evt Event) error {
-	if !d.running {
+	if !d.IsRunning() {
 		return fmt.Errorf("application not running")
 	}
-	d.events <- evt
+	select {
+	case d.events <- evt:
+	case <-d.stopping:
+		return fmt.Errorf("application stopped")
+	}
 	return nil
 }
 
+// EventQueueMetrics returns the counters of the queue of events waiting to
+// be processed
+func (d *CDisplayManager) EventQueueMetrics() EventQueueMetrics {
+	return d.process.Metrics()
+}
+
 func (d *CDisplayManager) pollEventWorker() {
-	for d.running {
-		if d.display != nil {
-			d.process <- d.display.PollEvent()
+	defer d.workers.Done()
+	d.Lock()
+	display := d.display
+	d.Unlock()
+	for display != nil {
+		evt := display.PollEvent()
+		select {
+		case <-d.stopping:
+			return
+		default:
+		}
+		if evt == nil {
+			// the display is closed
+			return
+		}
+		if d.holdForExternal(evt) {
+			continue
 		}
+		d.process.Push(evt)
 	}
 }
 
 func (d *CDisplayManager) processEventWorker() {
-	for d.running {
-		if evt := <-d.process; evt != nil {
-			if f := d.ProcessEvent(evt); f == EVENT_STOP {
-				// TODO: ProcessEvent must ONLY flag stop when UI changes
-				d.RequestDraw()
-				d.RequestShow()
-			}
+	defer d.workers.Done()
+	redraw := false
+	for d.IsRunning() {
+		evt := d.process.Pop()
+		if evt == nil {
+			// the queue is closed
+			return
+		}
+		if f := d.ProcessEvent(evt); f == EVENT_STOP {
+			// TODO: ProcessEvent must ONLY flag stop when UI changes
+			redraw = true
+		}
+		// draw once all the events waiting have been processed
+		if redraw && d.process.Len() == 0 {
+			redraw = false
+			d.RequestDraw()
+			d.RequestShow()
 		}
 	}
 }
 func (d *CDisplayManager) screenRequestWorker() {
-	if d.running {
-		if err := d.app.InitUI(); err != nil {
-			FatalDF(1, "%v", err)
+	defer d.workers.Done()
+	if d.IsRunning() {
+		if d.app != nil {
+			if err := d.app.InitUI(); err != nil {
+				FatalDF(1, "%v", err)
+			}
+		} else if d.initFn != nil {
+			// not owned by an application, failing to set up the UI
+			// only ends this display manager
+			if err := d.initFn(d); err != nil {
+				d.LogErr(err)
+				d.RequestQuit()
+			}
 		}
-
 	}
-	for d.running {
-		switch <-d.requests {
-		case DrawRequest:
-			if d.display != nil && !d.waiting {
-				d.DrawScreen()
+	// requests are merged until the next frame is due
+	var pending ScreenStateReq
+	var merged int
+	var frame *time.Timer
+	var due <-chan time.Time
+	schedule := func() {
+		if due == nil {
+			frame = time.NewTimer(d.nextFrameDelay())
+			due = frame.C
+		}
+	}
+	defer func() {
+		if frame != nil {
+			frame.Stop()
+		}
+	}()
+	for d.IsRunning() {
+		select {
+		case request := <-d.requests:
+			if request&QuitRequest != 0 {
+				select {
+				case d.done <- true:
+				case <-d.stopping:
+					return
+				}
+				continue
 			}
-		case ShowRequest:
-			if d.display != nil && !d.waiting {
-				d.display.Show()
+			pending |= request
+			merged++
+			schedule()
+		case <-due:
+			due = nil
+			if d.takeDrawNext() {
+				pending |= DrawRequest
 			}
-		case SyncRequest:
-			if d.display != nil && !d.waiting {
-				d.display.Sync()
+			d.renderFrame(pending, merged)
+			pending, merged = 0, 0
+			if !d.IsRenderDirtyOnly() {
+				schedule()
 			}
-		case QuitRequest:
-			d.done <- true
+		case <-d.stopping:
+			return
 		}
 	}
 }
 
 func (d *CDisplayManager) Run() error {
-	d.CaptureDisplay(d.ttyPath)
-	d.running = true
+	if !d.DisplayCaptured() {
+		d.CaptureDisplay(d.ttyPath)
+	}
+	d.quitLock.Lock()
+	atomic.StoreInt32(&d.running, 1)
+	quit := d.quitPending
+	d.quitPending = false
+	d.quitLock.Unlock()
+	d.workers.Add(3)
 	go d.pollEventWorker()
 	go d.processEventWorker()
 	go d.screenRequestWorker()
+	if quit {
+		// requested before running
+		d.RequestQuit()
+	}
 	defer func() {
+		// the workers are stopped before the display they use is released
+		d.stop()
+		d.process.Close()
+		d.Lock()
+		if d.display != nil {
+			// wake the event poller, a full queue wakes it just the same
+			_ = d.display.PostEvent(NewEventInterrupt(nil))
+		}
+		d.Unlock()
+		d.workers.Wait()
 		d.ReleaseDisplay()
-		close(d.done)
-		close(d.events)
-		close(d.queue)
 		if p := recover(); p != nil {
 			panic(p)
 		}
 	}()
-	AddTimeout(time.Millisecond*51, func() EventFlag {
-		if d.display != nil {
+	d.AddTimeout(time.Millisecond*51, func() EventFlag {
+		d.Lock()
+		display := d.display
+		if display != nil {
 			d.waiting = false
-			if err := d.display.PostEvent(NewEventResize(d.display.Size())); err != nil {
+		}
+		d.Unlock()
+		if display != nil {
+			if err := display.PostEvent(NewEventResize(display.Size())); err != nil {
 				Error(err)
 			}
 		}
@@ -570,21 +1213,13 @@ func (d *CDisplayManager) Run() error {
 	})
 	d.RequestDraw()
 	d.RequestSync()
-	for d.running {
+	for d.IsRunning() {
 		select {
-		case fn, ok := <-d.queue:
-			if !ok {
-				d.running = false
-				break
-			}
+		case fn := <-d.queue:
 			if err := fn(d); err != nil {
 				return err
 			}
-		case evt, ok := <-d.events:
-			if !ok {
-				d.running = false
-				break
-			}
+		case evt := <-d.events:
 			if d.display != nil {
 				if err := d.display.PostEvent(evt); err != nil {
 					Error(err)
@@ -593,13 +1228,38 @@ func (d *CDisplayManager) Run() error {
 				d.LogTrace("missing display, dropping event: %v", evt)
 			}
 		case <-d.done:
-			d.running = false
-			break
+			d.stop()
+		case <-d.stopping:
 		}
 	}
 	return nil
 }
 
+// stop flags the display manager as no longer running and closes the
+// stopping channel, once, waking everything waiting upon it
+func (d *CDisplayManager) stop() {
+	d.quitLock.Lock()
+	defer d.quitLock.Unlock()
+	atomic.StoreInt32(&d.running, 0)
+	if !d.stopped {
+		d.stopped = true
+		close(d.stopping)
+	}
+}
+
+// AddTimeout calls fn after the given delay, and again after each following
+// delay until fn returns EVENT_STOP. Timeouts belong to the display manager
+// and are all stopped when it is destroyed.
+func (d *CDisplayManager) AddTimeout(delay time.Duration, fn TimerCallbackFn) (id int) {
+	return d.timers.AddTimeout(delay, fn)
+}
+
+// CancelTimeout stops the timeout with the given id, as returned by
+// AddTimeout.
+func (d *CDisplayManager) CancelTimeout(id int) bool {
+	return d.timers.Stop(id)
+}
+
 func (d *CDisplayManager) IsRunning() bool {
-	return d.running
+	return atomic.LoadInt32(&d.running) != 0
 }
@@ -0,0 +1,380 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMultipleDisplayManagers(t *testing.T) {
+	Convey("Multiple display managers in one process", t, func() {
+		prev := GetDefaultDisplayManager()
+		defer SetDefaultDisplayManager(prev)
+
+		a := NewDisplayManager("first", OffscreenDisplayTtyPath)
+		b := NewDisplayManager("second", OffscreenDisplayTtyPath)
+		So(a, ShouldNotBeNil)
+		So(b, ShouldNotBeNil)
+		a.CaptureDisplay(OffscreenDisplayTtyPath)
+		b.CaptureDisplay(OffscreenDisplayTtyPath)
+		So(a.Display(), ShouldNotEqual, b.Display())
+
+		Convey("package helpers resolve to the default", func() {
+			SetDefaultDisplayManager(a)
+			So(GetDefaultDisplayManager(), ShouldEqual, a)
+			So(GetDisplayManager(), ShouldEqual, a)
+			SetCurrentTheme(DefaultMonoTheme)
+			So(GetCurrentTheme(), ShouldResemble, DefaultMonoTheme)
+			So(a.GetTheme(), ShouldResemble, DefaultMonoTheme)
+			So(b.GetTheme(), ShouldResemble, DefaultColorTheme)
+			SetDefaultDisplayManager(b)
+			So(GetCurrentTheme(), ShouldResemble, DefaultColorTheme)
+			SetDefaultDisplayManager(nil)
+			So(GetDisplayManager(), ShouldBeNil)
+			So(GetCurrentTheme(), ShouldResemble, DefaultColorTheme)
+		})
+
+		Convey("windows belong to one display manager", func() {
+			wa := NewWindow("a", nil)
+			wb := NewWindow("b", nil)
+			a.SetActiveWindow(wa)
+			b.SetActiveWindow(wb)
+			So(a.GetWindows(), ShouldHaveLength, 1)
+			So(b.GetWindows(), ShouldHaveLength, 1)
+			So(a.ActiveWindow(), ShouldEqual, wa)
+			So(b.ActiveWindow(), ShouldEqual, wb)
+			So(wa.GetDisplayManager(), ShouldEqual, a)
+			So(wb.GetDisplayManager(), ShouldEqual, b)
+		})
+
+		Convey("timeouts are stopped with their display manager", func() {
+			var fa, fb int32
+			a.AddTimeout(time.Millisecond*20, func() EventFlag {
+				atomic.AddInt32(&fa, 1)
+				return EVENT_STOP
+			})
+			b.AddTimeout(time.Millisecond*20, func() EventFlag {
+				atomic.AddInt32(&fb, 1)
+				return EVENT_STOP
+			})
+			id := a.AddTimeout(time.Millisecond*20, func() EventFlag {
+				atomic.AddInt32(&fa, 10)
+				return EVENT_STOP
+			})
+			So(a.CancelTimeout(id), ShouldBeTrue)
+			SetDefaultDisplayManager(b)
+			b.ReleaseDisplay()
+			b.Destroy()
+			So(GetDefaultDisplayManager(), ShouldBeNil)
+			time.Sleep(time.Millisecond * 60)
+			So(atomic.LoadInt32(&fa), ShouldEqual, 1)
+			So(atomic.LoadInt32(&fb), ShouldEqual, 0)
+		})
+
+		Convey("timeout IDs are not shared between display managers", func() {
+			var fa, fd int32
+			SetDefaultDisplayManager(nil)
+			early := AddTimeout(time.Millisecond*20, func() EventFlag {
+				atomic.AddInt32(&fd, 1)
+				return EVENT_STOP
+			})
+			SetDefaultDisplayManager(a)
+			id := AddTimeout(time.Millisecond*20, func() EventFlag {
+				atomic.AddInt32(&fa, 1)
+				return EVENT_STOP
+			})
+			So(id, ShouldNotEqual, early)
+			So(CancelTimeout(early), ShouldBeTrue)
+			So(CancelTimeout(early), ShouldBeFalse)
+			time.Sleep(time.Millisecond * 60)
+			So(atomic.LoadInt32(&fa), ShouldEqual, 1)
+			So(atomic.LoadInt32(&fd), ShouldEqual, 0)
+		})
+
+		Convey("mouse state is tracked per display", func() {
+			da := a.Display().(OffscreenDisplay)
+			db := b.Display().(OffscreenDisplay)
+			da.InjectMouse(1, 1, Button1, ModNone)
+			db.InjectMouse(1, 1, ButtonNone, ModNone)
+			ea, ok := da.PollEvent().(*EventMouse)
+			So(ok, ShouldBeTrue)
+			So(ea.State(), ShouldEqual, BUTTON_PRESS)
+			eb, ok := db.PollEvent().(*EventMouse)
+			So(ok, ShouldBeTrue)
+			So(eb.State(), ShouldEqual, MOUSE_MOVE)
+		})
+
+		a.ReleaseDisplay()
+		a.Destroy()
+		b.ReleaseDisplay()
+	})
+}
+
+func TestDisplayManagerDamage(t *testing.T) {
+	Convey("Rendering windows with damage tracking", t, func() {
+		d := NewDisplayManager("damage", OffscreenDisplayTtyPath)
+		defer d.Destroy()
+		d.CaptureDisplay(OffscreenDisplayTtyPath)
+		o := d.Display().(OffscreenDisplay)
+		o.SetSize(4, 2)
+		o.Show()
+		fill := func(w Window, r rune) {
+			w.Connect(SignalDraw, "damage-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+				if canvas, ok := argv[1].(Canvas); ok {
+					_ = canvas.SetRune(0, 0, r, StyleDefault)
+				}
+				return EVENT_STOP
+			})
+		}
+		wa := NewWindow("a", d)
+		wb := NewWindow("b", d)
+		fill(wa, 'a')
+		fill(wb, 'b')
+		d.AddWindow(wa)
+		d.AddWindow(wb)
+		firstRune := func() rune {
+			o.Show()
+			cells, _, _ := o.GetContents()
+			return cells[0].Runes[0]
+		}
+
+		d.SetActiveWindow(wa)
+		So(d.DrawScreen(), ShouldEqual, EVENT_STOP)
+		So(firstRune(), ShouldEqual, 'a')
+		d.SetActiveWindow(wb)
+		So(d.DrawScreen(), ShouldEqual, EVENT_STOP)
+		So(firstRune(), ShouldEqual, 'b')
+		// switching back renders the whole window, though unchanged
+		d.SetActiveWindow(wa)
+		So(d.DrawScreen(), ShouldEqual, EVENT_STOP)
+		So(firstRune(), ShouldEqual, 'a')
+		// the display changed by other means is drawn over once invalidated
+		o.SetContent(0, 0, 'x', nil, StyleDefault)
+		So(firstRune(), ShouldEqual, 'x')
+		So(d.DrawScreen(), ShouldEqual, EVENT_STOP)
+		So(firstRune(), ShouldEqual, 'x')
+		d.Invalidate(MakeRegion(0, 0, 1, 1))
+		So(d.DrawScreen(), ShouldEqual, EVENT_STOP)
+		So(firstRune(), ShouldEqual, 'a')
+	})
+}
+
+func TestDisplayManagerPaste(t *testing.T) {
+	Convey("Buffering bracketed pastes", t, func() {
+		d := NewDisplayManager("paste", OffscreenDisplayTtyPath)
+		defer d.Destroy()
+		buffer, limit := d.GetPasteBuffering()
+		So(buffer, ShouldBeFalse)
+		So(limit, ShouldEqual, DefaultPasteLimit)
+		d.SetPasteBuffering(true, 0)
+		var events []Event
+		d.Connect(SignalEvent, "paste-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+			events = append(events, argv[1].(Event))
+			return EVENT_PASS
+		})
+		d.Connect(SignalEventKey, "paste-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+			events = append(events, argv[1].(Event))
+			return EVENT_PASS
+		})
+		d.Connect(SignalEventPaste, "paste-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+			events = append(events, argv[1].(Event))
+			return EVENT_PASS
+		})
+		paste := func(text string) {
+			d.ProcessEvent(NewEventPaste(true))
+			for _, r := range text {
+				d.ProcessEvent(NewEventKey(KeyRune, r, ModNone))
+			}
+			d.ProcessEvent(NewEventPaste(false))
+		}
+
+		Convey("delivers the text of a paste at once", func() {
+			paste("one\r\ntwo\tthree\rfour")
+			So(events, ShouldHaveLength, 1)
+			ep, ok := events[0].(*EventPaste)
+			So(ok, ShouldBeTrue)
+			So(ep.HasText(), ShouldBeTrue)
+			So(ep.Text(), ShouldEqual, "one\ntwo\tthree\nfour")
+			So(ep.Len(), ShouldEqual, 18)
+		})
+
+		Convey("delivers other events during a paste", func() {
+			d.ProcessEvent(NewEventPaste(true))
+			d.ProcessEvent(NewEventKey(KeyRune, 'a', ModNone))
+			d.ProcessEvent(NewEventInterrupt(nil))
+			d.ProcessEvent(NewEventPaste(false))
+			So(events, ShouldHaveLength, 2)
+			So(events[0], ShouldHaveSameTypeAs, &EventInterrupt{})
+			So(events[1].(*EventPaste).Text(), ShouldEqual, "a")
+		})
+
+		Convey("delivers pastes over the limit key by key", func() {
+			d.SetPasteBuffering(true, 4)
+			paste("abcdefg")
+			So(events, ShouldHaveLength, 9)
+			So(events[0].(*EventPaste).Start(), ShouldBeTrue)
+			for i, r := range "abcdefg" {
+				So(events[i+1].(*EventKey).Rune(), ShouldEqual, r)
+			}
+			So(events[8].(*EventPaste).End(), ShouldBeTrue)
+			// the next paste is buffered again
+			events = nil
+			paste("abc")
+			So(events, ShouldHaveLength, 1)
+			So(events[0].(*EventPaste).Text(), ShouldEqual, "abc")
+		})
+
+		Convey("delivers pastes key by key when not buffering", func() {
+			d.SetPasteBuffering(false, 0)
+			buffer, limit = d.GetPasteBuffering()
+			So(buffer, ShouldBeFalse)
+			So(limit, ShouldEqual, DefaultPasteLimit)
+			paste("ab")
+			So(events, ShouldHaveLength, 4)
+			So(events[0].(*EventPaste).Start(), ShouldBeTrue)
+			So(events[3].(*EventPaste).End(), ShouldBeTrue)
+		})
+	})
+}
+
+func TestDisplayManagerTitle(t *testing.T) {
+	Convey("Showing titles upon the display", t, func() {
+		d := NewDisplayManager("manager", OffscreenDisplayTtyPath)
+		defer d.Destroy()
+		var titles []string
+		d.Connect(SignalDisplayTitle, "title-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+			titles = append(titles, argv[1].(string))
+			return EVENT_PASS
+		})
+		d.CaptureDisplay(OffscreenDisplayTtyPath)
+		o := d.Display().(OffscreenDisplay)
+		So(o.GetTitle(), ShouldEqual, "manager")
+
+		w := NewWindow("", d)
+		d.SetActiveWindow(w)
+		d.DrawScreen()
+		// windows without a title show that of the display manager
+		So(titles, ShouldResemble, []string{"manager"})
+		w.SetTitle("window")
+		d.DrawScreen()
+		So(o.GetTitle(), ShouldEqual, "window")
+		d.DrawScreen()
+		So(titles, ShouldResemble, []string{"manager", "window"})
+		d.SetTitle("renamed")
+		So(d.GetTitle(), ShouldEqual, "renamed")
+		So(titles, ShouldHaveLength, 2)
+		w.SetTitle("")
+		d.DrawScreen()
+		So(o.GetTitle(), ShouldEqual, "renamed")
+		d.ReleaseDisplay()
+		So(o.GetTitle(), ShouldEqual, "renamed")
+		So(titles, ShouldResemble, []string{"manager", "window", "renamed"})
+	})
+
+	Convey("Listeners of title changes calling upon the display manager", t, func() {
+		d := NewDisplayManager("manager", OffscreenDisplayTtyPath)
+		defer d.Destroy()
+		var titles []string
+		d.Connect(SignalDisplayTitle, "title-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+			title := argv[1].(string)
+			titles = append(titles, title)
+			if title == "manager" {
+				d.SetTitle("retitled")
+			}
+			return EVENT_PASS
+		})
+		done := make(chan struct{})
+		go func() {
+			d.CaptureDisplay(OffscreenDisplayTtyPath)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			So("deadlocked", ShouldBeEmpty)
+		}
+		So(titles, ShouldResemble, []string{"manager", "retitled"})
+		So(d.Display().(OffscreenDisplay).GetTitle(), ShouldEqual, "retitled")
+		d.ReleaseDisplay()
+	})
+}
+
+func TestDisplayManagerFocus(t *testing.T) {
+	Convey("Delivering focus changes", t, func() {
+		d := NewDisplayManager("focus", OffscreenDisplayTtyPath)
+		defer d.Destroy()
+		d.CaptureDisplay(OffscreenDisplayTtyPath)
+		defer d.ReleaseDisplay()
+		w := NewWindow("focus", d)
+		d.SetActiveWindow(w)
+		var windowSaw, managerSaw []bool
+		w.Connect(SignalEvent, "focus-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+			if e, ok := argv[1].(*EventFocus); ok {
+				windowSaw = append(windowSaw, e.Focused())
+			}
+			return EVENT_PASS
+		})
+		d.Connect(SignalEventFocus, "focus-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+			managerSaw = append(managerSaw, argv[1].(*EventFocus).Focused())
+			return EVENT_PASS
+		})
+		d.ProcessEvent(NewEventFocus(false))
+		d.ProcessEvent(NewEventFocus(true))
+		So(windowSaw, ShouldResemble, []bool{false, true})
+		So(managerSaw, ShouldResemble, []bool{false, true})
+	})
+}
+
+func TestDisplayManagerQuitBeforeRun(t *testing.T) {
+	Convey("Quitting before running", t, func() {
+		d := NewDisplayManager("quit", OffscreenDisplayTtyPath)
+		defer d.Destroy()
+		d.CaptureDisplay(OffscreenDisplayTtyPath)
+		d.RequestQuit()
+		stopped := make(chan error)
+		go func() {
+			stopped <- d.Run()
+		}()
+		select {
+		case err := <-stopped:
+			So(err, ShouldBeNil)
+		case <-time.After(time.Second):
+			So("still running", ShouldBeEmpty)
+		}
+	})
+}
+
+func TestDisplayManagerCaptureCtrlC(t *testing.T) {
+	Convey("Capturing Ctrl+C", t, func() {
+		d := NewDisplayManager("ctrl-c", OffscreenDisplayTtyPath)
+		defer d.Destroy()
+		d.CaptureDisplay(OffscreenDisplayTtyPath)
+		defer d.ReleaseDisplay()
+		d.CaptureCtrlC()
+		interrupts := 0
+		d.Connect(SignalInterrupt, "ctrl-c-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+			interrupts++
+			return EVENT_STOP
+		})
+		So(d.ProcessEvent(NewEventKey(KeyCtrlC, rune(KeyCtrlC), ModCtrl)), ShouldEqual, EVENT_STOP)
+		d.ProcessEvent(NewEventKeyType(KeyCtrlC, rune(KeyCtrlC), ModCtrl, KeyEventRepeat))
+		d.ProcessEvent(NewEventKeyType(KeyCtrlC, rune(KeyCtrlC), ModCtrl, KeyEventRelease))
+		So(interrupts, ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,47 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"time"
+)
+
+// EventClipboard reports the contents of a clipboard selection, in reply to
+// a request made with Clipboard.RequestText (or Display.RequestClipboard).
+type EventClipboard struct {
+	t         time.Time
+	selection ClipboardSelection
+	text      string
+}
+
+// When returns the time when this EventClipboard was created.
+func (ev *EventClipboard) When() time.Time {
+	return ev.t
+}
+
+// Selection returns the clipboard selection the text was read from.
+func (ev *EventClipboard) Selection() ClipboardSelection {
+	return ev.selection
+}
+
+// Text returns the contents of the clipboard selection.
+func (ev *EventClipboard) Text() string {
+	return ev.text
+}
+
+// NewEventClipboard returns a new EventClipboard.
+func NewEventClipboard(selection ClipboardSelection, text string) *EventClipboard {
+	return &EventClipboard{t: time.Now(), selection: selection, text: text}
+}
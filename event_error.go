@@ -49,6 +49,11 @@ var (
 	// ErrEventQFull indicates that the event queue is full, and
 	// cannot accept more events.
 	ErrEventQFull = errors.New("event queue full")
+
+	// ErrNoTty indicates that the display is not on a tty, such as an
+	// offscreen display or one on the stream of an SSH session, and so
+	// there is no terminal to run another program upon.
+	ErrNoTty = errors.New("display is not on a tty")
 )
 
 // An EventError is an event representing some sort of error, and carries
@@ -0,0 +1,42 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"time"
+)
+
+// EventFocus is sent when the terminal gains or loses the input focus of
+// the user's desktop, if the display has focus reporting enabled, see
+// Display.EnableFocus.
+type EventFocus struct {
+	focused bool
+	t       time.Time
+}
+
+// When returns the time when this EventFocus was created.
+func (ev *EventFocus) When() time.Time {
+	return ev.t
+}
+
+// Focused returns true if the terminal gained focus, false if lost.
+func (ev *EventFocus) Focused() bool {
+	return ev.focused
+}
+
+// NewEventFocus returns a new EventFocus.
+func NewEventFocus(focused bool) *EventFocus {
+	return &EventFocus{t: time.Now(), focused: focused}
+}
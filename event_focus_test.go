@@ -0,0 +1,35 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEventFocus(t *testing.T) {
+	Convey("EventFocus basics", t, func() {
+		then := time.Now()
+		ef := NewEventFocus(true)
+		So(ef, ShouldHaveSameTypeAs, &EventFocus{})
+		now := time.Now()
+		So(ef.When().UnixNano(), ShouldBeGreaterThanOrEqualTo, then.UnixNano())
+		So(ef.When().UnixNano(), ShouldBeLessThanOrEqualTo, now.UnixNano())
+		So(ef.Focused(), ShouldBeTrue)
+		So(NewEventFocus(false).Focused(), ShouldBeFalse)
+	})
+}
@@ -43,11 +43,45 @@ import (
 // activity than graphical applications.  Hence, they should avoid depending
 // overly much on availability of modifiers, or the availability of any
 // specific keys.
+//
+// Terminals using the kitty keyboard protocol are the exception, see
+// Display.EnableKittyKeyboard.  These report all modifiers, can report the
+// repeating and release of keys, and the keys the shifted and base layout
+// keys produce.  Control keys are reported with ModCtrl set, Ctrl+A is KeyCtrlA
+// with ModCtrl for instance, except those sharing their code with Tab, Enter,
+// Esc and Backspace.  These are runes with ModCtrl instead, such that Ctrl+I
+// is KeyRune 'i' with ModCtrl while Ctrl+Tab is KeyTab with ModCtrl.
 type EventKey struct {
-	t   time.Time
-	mod ModMask
-	key Key
-	ch  rune
+	t       time.Time
+	mod     ModMask
+	key     Key
+	ch      rune
+	event   KeyEventType
+	shifted rune
+	base    rune
+}
+
+// KeyEventType is whether an EventKey is for the pressing, repeating or
+// releasing of a key.  Only terminals using the kitty keyboard protocol
+// report the repeating and releasing of keys.
+type KeyEventType int
+
+const (
+	KeyEventPress KeyEventType = iota
+	KeyEventRepeat
+	KeyEventRelease
+)
+
+func (t KeyEventType) String() string {
+	switch t {
+	case KeyEventPress:
+		return "press"
+	case KeyEventRepeat:
+		return "repeat"
+	case KeyEventRelease:
+		return "release"
+	}
+	return fmt.Sprintf("key-event-type(%d)", int(t))
 }
 
 // When returns the time when this Event was created, which should closely
@@ -79,6 +113,25 @@ func (ev *EventKey) Modifiers() ModMask {
 	return ev.mod
 }
 
+// EventType returns whether the key was pressed, repeated or released.
+func (ev *EventKey) EventType() KeyEventType {
+	return ev.event
+}
+
+// ShiftedRune returns the rune of the key when shifted, if reported by the
+// terminal, otherwise zero.
+func (ev *EventKey) ShiftedRune() rune {
+	return ev.shifted
+}
+
+// BaseRune returns the rune of the key in the standard PC-101 layout, if
+// reported by the terminal and different to the key pressed, otherwise
+// zero.  This is useful for keyboard shortcuts that work irrespective of
+// the keyboard layout of the user.
+func (ev *EventKey) BaseRune() rune {
+	return ev.base
+}
+
 // KeyNames holds the written names of special keys. Useful to echo back a key
 // name, or to look up a key from a string value.
 var KeyNames = map[Key]string{
@@ -216,6 +269,12 @@ func (ev *EventKey) Name() string {
 	if ev.mod&ModMeta != 0 {
 		m = append(m, "Meta")
 	}
+	if ev.mod&ModSuper != 0 {
+		m = append(m, "Super")
+	}
+	if ev.mod&ModHyper != 0 {
+		m = append(m, "Hyper")
+	}
 	if ev.mod&ModCtrl != 0 {
 		m = append(m, "Ctrl")
 	}
@@ -259,6 +318,13 @@ func NewEventKey(k Key, ch rune, mod ModMask) *EventKey {
 	return &EventKey{t: time.Now(), key: k, ch: ch, mod: mod}
 }
 
+// NewEventKeyType creates an event for the pressing, repeating or releasing
+// of a key.  Unlike NewEventKey, the key and modifiers given are used as
+// they are.
+func NewEventKeyType(k Key, ch rune, mod ModMask, event KeyEventType) *EventKey {
+	return &EventKey{t: time.Now(), key: k, ch: ch, mod: mod, event: event}
+}
+
 // ModMask is a mask of modifier keys.  Note that it will not always be
 // possible to report modifier keys.
 type ModMask int16
@@ -273,6 +339,8 @@ const (
 	ModCtrl
 	ModAlt
 	ModMeta
+	ModSuper
+	ModHyper
 	ModNone ModMask = 0
 )
 
@@ -384,6 +452,8 @@ const (
 	// These key codes are used internally, and will never appear to applications.
 	keyPasteStart Key = iota + 16384
 	keyPasteEnd
+	keyFocusIn
+	keyFocusOut
 )
 
 // These are the control keys.  Note that they overlap with other keys,
@@ -473,3 +543,25 @@ const (
 	KeyEnter      = KeyCR
 	KeyBackspace2 = KeyDEL
 )
+
+// controlKey returns the control key the given key produces when pressed
+// with Ctrl, as reported by terminals without the kitty keyboard protocol
+func controlKey(r rune) (Key, bool) {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return Key(r - 'a' + 1), true
+	case r == ' ', r == '@':
+		return KeyCtrlSpace, true
+	case r == '[':
+		return KeyEsc, true
+	case r == '\\':
+		return KeyCtrlBackslash, true
+	case r == ']':
+		return KeyCtrlRightSq, true
+	case r == '^':
+		return KeyCtrlCarat, true
+	case r == '_':
+		return KeyCtrlUnderscore, true
+	}
+	return 0, false
+}
@@ -66,5 +66,12 @@ func TestKey(t *testing.T) {
 		So(ek.Name(), ShouldEqual, "Ctrl-Space")
 		ek = NewEventKey(KeyCtrlSpace, rune(KeyCtrlSpace), ModCtrl)
 		So(ek.Name(), ShouldEqual, "Ctrl+Space")
+		So(ek.EventType(), ShouldEqual, KeyEventPress)
+		ek = NewEventKeyType(KeyRune, 'a', ModSuper|ModHyper, KeyEventRelease)
+		So(ek.Name(), ShouldEqual, "Super+Hyper+Rune[a]")
+		So(ek.EventType(), ShouldEqual, KeyEventRelease)
+		So(ek.EventType().String(), ShouldEqual, "release")
+		So(ek.ShiftedRune(), ShouldEqual, 0)
+		So(ek.BaseRune(), ShouldEqual, 0)
 	})
 }
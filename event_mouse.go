@@ -34,8 +34,9 @@ import (
 // Most terminals cannot report the state of more than one button at a time --
 // and some cannot report motion events unless a button is pressed.
 //
-// Applications can inspect the time between events to resolve double or
-// triple clicks.
+// The display manager counts the clicks of a button made in quick succession
+// at about the same position, see ClickCount, and follows the release of a
+// button with a MOUSE_CLICK, MOUSE_DOUBLE_CLICK or MOUSE_TRIPLE_CLICK event.
 type EventMouse struct {
 	t   time.Time
 	btn ButtonMask
@@ -44,6 +45,7 @@ type EventMouse struct {
 	y   int
 	s   MouseState
 	b   ButtonMask
+	n   int
 }
 
 var (
@@ -56,6 +58,10 @@ var (
 		DRAG_START:     "DragStart",
 		DRAG_MOVE:      "DragMove",
 		DRAG_STOP:      "DragStop",
+
+		MOUSE_CLICK:        "Click",
+		MOUSE_DOUBLE_CLICK: "DoubleClick",
+		MOUSE_TRIPLE_CLICK: "TripleClick",
 	}
 	previous_event_mouse *EventMouse = &EventMouse{
 		t:   time.Now(),
@@ -70,7 +76,19 @@ var (
 
 // NewEventMouse is used to create a new mouse event.  Applications
 // shouldn't need to use this; its mostly for display implementors.
+//
+// The mouse state of the event is derived from the last event made with
+// NewEventMouse, displays track their own previous mouse event instead so
+// that one display does not influence the mouse state of another.
 func NewEventMouse(x, y int, btn ButtonMask, mod ModMask) *EventMouse {
+	em := newEventMouse(previous_event_mouse, x, y, btn, mod)
+	previous_event_mouse = em
+	return em
+}
+
+// newEventMouse creates a mouse event that follows on from the previous
+// event given, a nil previous event is the same as no mouse activity
+func newEventMouse(pem *EventMouse, x, y int, btn ButtonMask, mod ModMask) *EventMouse {
 	em := &EventMouse{
 		t:   time.Now(),
 		x:   x,
@@ -80,7 +98,16 @@ func NewEventMouse(x, y int, btn ButtonMask, mod ModMask) *EventMouse {
 		s:   MOUSE_NONE,
 		b:   ButtonNone,
 	}
-	em.process_mouse_event()
+	if pem == nil {
+		pem = &EventMouse{
+			t:   em.t,
+			btn: ButtonNone,
+			mod: ModNone,
+			s:   MOUSE_NONE,
+			b:   ButtonNone,
+		}
+	}
+	em.process_mouse_event(pem)
 	return em
 }
 
@@ -146,6 +173,20 @@ func (ev *EventMouse) IsDragStopped() bool {
 	return ev.s.Has(DRAG_STOP)
 }
 
+// IsClicked returns true for the click events following the release of a
+// button, of any number of clicks.
+func (ev *EventMouse) IsClicked() bool {
+	return ev.s.Has(MOUSE_CLICK | MOUSE_DOUBLE_CLICK | MOUSE_TRIPLE_CLICK)
+}
+
+// ClickCount returns the number of clicks of the button made in quick
+// succession, at about the same position, of which the press, release or
+// click event is part.  Other events, and mouse events not processed by a
+// display manager, have a click count of zero.
+func (ev *EventMouse) ClickCount() int {
+	return ev.n
+}
+
 func (ev *EventMouse) IsWheelImpulse() bool {
 	return ev.s.Has(WHEEL_PULSE)
 }
@@ -219,9 +260,7 @@ func (ev *EventMouse) Report() string {
 	DRAG_STOP: button released and mouse may have moved
 
 */
-func (ev *EventMouse) process_mouse_event() {
-	pem := previous_event_mouse
-	previous_event_mouse = ev
+func (ev *EventMouse) process_mouse_event(pem *EventMouse) {
 	ev.s = MOUSE_NONE
 	ev.b = ButtonNone
 
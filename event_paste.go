@@ -23,9 +23,15 @@ import (
 // An event with .MainInit() true will be sent to mark the start.
 // Then a number of keys will be sent to indicate that the content
 // is pasted in.  At the end, an event with .MainInit() false will be sent.
+//
+// When the display manager buffers pastes, see SetPasteBuffering, the start,
+// keys and end are instead delivered as one event carrying the pasted text,
+// for which HasText() is true and both Start() and End() are false.
 type EventPaste struct {
-	start bool
-	t     time.Time
+	start   bool
+	content bool
+	text    string
+	t       time.Time
 }
 
 // When returns the time when this EventMouse was created.
@@ -40,10 +46,30 @@ func (ev *EventPaste) Start() bool {
 
 // End returns true if this is the end of a paste.
 func (ev *EventPaste) End() bool {
-	return !ev.start
+	return !ev.start && !ev.content
+}
+
+// HasText returns true if this event carries the whole of the pasted text.
+func (ev *EventPaste) HasText() bool {
+	return ev.content
+}
+
+// Text returns the pasted text, if HasText() is true.
+func (ev *EventPaste) Text() string {
+	return ev.text
+}
+
+// Len returns the length of the pasted text, in bytes.
+func (ev *EventPaste) Len() int {
+	return len(ev.text)
 }
 
 // NewEventPaste returns a new EventPaste.
 func NewEventPaste(start bool) *EventPaste {
 	return &EventPaste{t: time.Now(), start: start}
 }
+
+// NewEventPasteText returns a new EventPaste carrying the pasted text.
+func NewEventPasteText(text string) *EventPaste {
+	return &EventPaste{t: time.Now(), content: true, text: text}
+}
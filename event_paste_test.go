@@ -34,5 +34,12 @@ func TestEventPaste(t *testing.T) {
 		ep = NewEventPaste(true)
 		So(ep.Start(), ShouldEqual, true)
 		So(ep.End(), ShouldEqual, false)
+		So(ep.HasText(), ShouldEqual, false)
+		ep = NewEventPasteText("pasted\ntext")
+		So(ep.Start(), ShouldEqual, false)
+		So(ep.End(), ShouldEqual, false)
+		So(ep.HasText(), ShouldEqual, true)
+		So(ep.Text(), ShouldEqual, "pasted\ntext")
+		So(ep.Len(), ShouldEqual, 11)
 	})
 }
@@ -1,3 +1,263 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
 package cdk
 
+import (
+	"sync"
+)
+
 // manages display event queue
+
+var (
+	// EventQueueCapacity is the most events an event queue holds, once full
+	// the oldest normal priority event, or the oldest event if there are
+	// none, is dropped to make room
+	EventQueueCapacity = 1024
+)
+
+// EventPriority determines the order events are taken from an event queue,
+// higher priority events are taken before lower priority events pushed
+// earlier, except for mouse events which never overtake mouse motion, such
+// that a drag is seen in order.  Events of the same priority are taken in the
+// order they were pushed.  Lower priority events are also dropped first from
+// an event queue that is full.
+type EventPriority int
+
+const (
+	EventPriorityNormal EventPriority = iota
+	EventPriorityHigh
+)
+
+// GetEventPriority returns the priority of the given event.  Mouse motion
+// has normal priority, so that keys are not kept waiting behind it and the
+// next motion event makes up for one dropped, all others are high.
+func GetEventPriority(evt Event) EventPriority {
+	if e, ok := evt.(*EventMouse); ok && isMotionEvent(e) {
+		return EventPriorityNormal
+	}
+	return EventPriorityHigh
+}
+
+// coalesceEvents returns true if next supersedes prev, such that only next
+// needs to be delivered.  Resizes supersede resizes and mouse motion
+// supersedes motion with the same buttons and modifiers held.
+func coalesceEvents(prev, next Event) bool {
+	switch n := next.(type) {
+	case *EventResize:
+		_, ok := prev.(*EventResize)
+		return ok
+	case *EventMouse:
+		if p, ok := prev.(*EventMouse); ok {
+			return isMotionEvent(p) && isMotionEvent(n) &&
+				p.State() == n.State() &&
+				p.Buttons() == n.Buttons() &&
+				p.Modifiers() == n.Modifiers()
+		}
+	}
+	return false
+}
+
+func isMotionEvent(evt *EventMouse) bool {
+	return evt.State() == MOUSE_MOVE || evt.State() == DRAG_MOVE
+}
+
+// EventQueueMetrics are the counters kept by an event queue
+type EventQueueMetrics struct {
+	// number of events waiting in the queue
+	Depth int
+	// largest number of events waiting in the queue at any one time
+	MaxDepth int
+	// number of events pushed, including those coalesced and dropped
+	Pushed uint64
+	// number of events taken from the queue
+	Popped uint64
+	// number of events superseded by a following event
+	Coalesced uint64
+	// number of events dropped because the queue was full
+	Dropped uint64
+}
+
+// EventQueue is a prioritized, coalescing queue of events.  Pushing never
+// blocks, consecutive resizes and mouse motion events are coalesced into the
+// last one and keys are taken before mouse motion, see EventPriority.  Mouse
+// motion is dropped first when full.
+type EventQueue interface {
+	Push(evt Event)
+	Pop() Event
+	TryPop() (evt Event, ok bool)
+	Len() int
+	Metrics() EventQueueMetrics
+	Close()
+	IsClosed() bool
+}
+
+type CEventQueue struct {
+	events   []Event
+	capacity int
+	metrics  EventQueueMetrics
+	closed   bool
+	ready    chan struct{}
+	done     chan struct{}
+
+	sync.Mutex
+}
+
+// NewEventQueue returns an empty event queue holding at most capacity
+// events, a capacity less than one uses EventQueueCapacity.
+func NewEventQueue(capacity int) *CEventQueue {
+	if capacity < 1 {
+		capacity = EventQueueCapacity
+	}
+	return &CEventQueue{
+		capacity: capacity,
+		ready:    make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+}
+
+// Push adds the given event to the queue, nil events and events pushed after
+// the queue is closed are ignored.
+func (q *CEventQueue) Push(evt Event) {
+	if evt == nil {
+		return
+	}
+	q.Lock()
+	if q.closed {
+		q.Unlock()
+		return
+	}
+	q.metrics.Pushed++
+	if n := len(q.events); n > 0 && coalesceEvents(q.events[n-1], evt) {
+		q.events[n-1] = evt
+		q.metrics.Coalesced++
+	} else {
+		if len(q.events) >= q.capacity {
+			q.drop()
+		}
+		q.events = append(q.events, evt)
+	}
+	if depth := len(q.events); depth > q.metrics.MaxDepth {
+		q.metrics.MaxDepth = depth
+	}
+	q.Unlock()
+	select {
+	case q.ready <- struct{}{}:
+	default:
+		// already flagged as ready
+	}
+}
+
+// drop discards the oldest event of the lowest priority that has any
+func (q *CEventQueue) drop() {
+	if len(q.events) == 0 {
+		return
+	}
+	idx := 0
+	lowest := GetEventPriority(q.events[0])
+	for i, evt := range q.events {
+		if p := GetEventPriority(evt); p < lowest {
+			idx, lowest = i, p
+		}
+	}
+	copy(q.events[idx:], q.events[idx+1:])
+	q.events[len(q.events)-1] = nil
+	q.events = q.events[:len(q.events)-1]
+	q.metrics.Dropped++
+}
+
+// Pop waits for and returns the next event in the queue.  Returns nil once
+// the queue is closed.
+func (q *CEventQueue) Pop() Event {
+	for {
+		if evt, ok := q.TryPop(); ok {
+			return evt
+		}
+		select {
+		case <-q.ready:
+		case <-q.done:
+			return nil
+		}
+	}
+}
+
+// TryPop returns the next event in the queue without waiting, ok is false if
+// the queue is empty or closed.
+func (q *CEventQueue) TryPop() (evt Event, ok bool) {
+	q.Lock()
+	defer q.Unlock()
+	if q.closed {
+		return nil, false
+	}
+	if len(q.events) == 0 {
+		return nil, false
+	}
+	idx := q.next()
+	evt = q.events[idx]
+	copy(q.events[1:idx+1], q.events[:idx])
+	q.events[0] = nil
+	q.events = q.events[1:]
+	q.metrics.Popped++
+	return evt, true
+}
+
+// next returns the index of the next event to take, the oldest of those with
+// the highest priority unless a mouse event would overtake mouse motion
+func (q *CEventQueue) next() (idx int) {
+	highest := GetEventPriority(q.events[0])
+	for i := 1; i < len(q.events) && highest < EventPriorityHigh; i++ {
+		evt := q.events[i]
+		if p := GetEventPriority(evt); p > highest {
+			if _, ok := evt.(*EventMouse); ok {
+				break
+			}
+			idx, highest = i, p
+		}
+	}
+	return
+}
+
+// Len returns the number of events waiting in the queue
+func (q *CEventQueue) Len() int {
+	q.Lock()
+	defer q.Unlock()
+	return len(q.events)
+}
+
+func (q *CEventQueue) Metrics() EventQueueMetrics {
+	q.Lock()
+	defer q.Unlock()
+	m := q.metrics
+	m.Depth = len(q.events)
+	return m
+}
+
+// Close discards any events waiting in the queue and wakes any waiting in
+// Pop.  Closing a closed queue does nothing.
+func (q *CEventQueue) Close() {
+	q.Lock()
+	defer q.Unlock()
+	if q.closed {
+		return
+	}
+	q.closed = true
+	q.events = nil
+	close(q.done)
+}
+
+func (q *CEventQueue) IsClosed() bool {
+	q.Lock()
+	defer q.Unlock()
+	return q.closed
+}
@@ -0,0 +1,158 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEventQueue(t *testing.T) {
+	Convey("Prioritized, coalescing event queues", t, func() {
+		q := NewEventQueue(0)
+		defer q.Close()
+		So(q.capacity, ShouldEqual, EventQueueCapacity)
+		evt, ok := q.TryPop()
+		So(ok, ShouldBeFalse)
+		So(evt, ShouldBeNil)
+
+		Convey("coalesce consecutive resizes", func() {
+			q.Push(NewEventResize(10, 10))
+			q.Push(NewEventResize(20, 10))
+			q.Push(NewEventResize(30, 10))
+			So(q.Len(), ShouldEqual, 1)
+			evt, ok = q.TryPop()
+			So(ok, ShouldBeTrue)
+			w, _ := evt.(*EventResize).Size()
+			So(w, ShouldEqual, 30)
+			m := q.Metrics()
+			So(m.Pushed, ShouldEqual, 3)
+			So(m.Coalesced, ShouldEqual, 2)
+			So(m.Popped, ShouldEqual, 1)
+			So(m.Depth, ShouldEqual, 0)
+			So(m.MaxDepth, ShouldEqual, 1)
+		})
+
+		Convey("coalesce consecutive motion with the same buttons", func() {
+			var pem *EventMouse
+			mouse := func(x, y int, btn ButtonMask) *EventMouse {
+				pem = newEventMouse(pem, x, y, btn, ModNone)
+				return pem
+			}
+			q.Push(mouse(1, 1, ButtonNone))
+			q.Push(mouse(2, 1, ButtonNone))
+			q.Push(mouse(3, 1, ButtonNone))
+			q.Push(mouse(3, 1, Button1))
+			q.Push(mouse(4, 1, Button1))
+			q.Push(mouse(5, 1, Button1))
+			q.Push(mouse(6, 1, Button1))
+			q.Push(mouse(6, 1, ButtonNone))
+			var states []MouseState
+			var xs []int
+			for q.Len() > 0 {
+				evt, _ = q.TryPop()
+				em := evt.(*EventMouse)
+				x, _ := em.Position()
+				xs = append(xs, x)
+				states = append(states, em.State())
+			}
+			So(xs, ShouldResemble, []int{3, 3, 4, 6, 6})
+			So(states[0], ShouldEqual, MOUSE_MOVE)
+			So(states[3], ShouldEqual, DRAG_MOVE)
+		})
+
+		Convey("take keys before motion", func() {
+			q.Push(newEventMouse(nil, 1, 1, ButtonNone, ModNone))
+			q.Push(NewEventKey(KeyRune, 'a', ModNone))
+			q.Push(NewEventKey(KeyRune, 'b', ModNone))
+			evt, _ = q.TryPop()
+			So(evt.(*EventKey).Rune(), ShouldEqual, 'a')
+			evt, _ = q.TryPop()
+			So(evt.(*EventKey).Rune(), ShouldEqual, 'b')
+			evt, _ = q.TryPop()
+			So(evt.(*EventMouse).State(), ShouldEqual, MOUSE_MOVE)
+		})
+
+		Convey("keep mouse buttons behind motion", func() {
+			q.Push(newEventMouse(nil, 1, 1, ButtonNone, ModNone))
+			q.Push(newEventMouse(nil, 1, 1, Button1, ModNone))
+			q.Push(NewEventKey(KeyRune, 'a', ModNone))
+			evt, _ = q.TryPop()
+			So(evt.(*EventMouse).State(), ShouldEqual, MOUSE_MOVE)
+			evt, _ = q.TryPop()
+			So(evt.(*EventMouse).State(), ShouldEqual, BUTTON_PRESS)
+			evt, _ = q.TryPop()
+			So(evt.(*EventKey).Rune(), ShouldEqual, 'a')
+		})
+
+		Convey("take events of the same priority in the order pushed", func() {
+			q.Push(newEventMouse(nil, 1, 1, Button1, ModNone))
+			q.Push(NewEventKey(KeyRune, 'a', ModNone))
+			q.Push(NewEventResize(20, 10))
+			evt, _ = q.TryPop()
+			So(evt.(*EventMouse).State(), ShouldEqual, BUTTON_PRESS)
+			evt, _ = q.TryPop()
+			So(evt.(*EventKey).Rune(), ShouldEqual, 'a')
+			evt, _ = q.TryPop()
+			w, _ := evt.(*EventResize).Size()
+			So(w, ShouldEqual, 20)
+		})
+
+		Convey("drop motion first when full", func() {
+			small := NewEventQueue(2)
+			small.Push(NewEventKey(KeyRune, 'a', ModNone))
+			small.Push(newEventMouse(nil, 1, 1, ButtonNone, ModNone))
+			small.Push(NewEventKey(KeyRune, 'b', ModNone))
+			So(small.Metrics().Dropped, ShouldEqual, 1)
+			evt, _ = small.TryPop()
+			So(evt.(*EventKey).Rune(), ShouldEqual, 'a')
+			evt, _ = small.TryPop()
+			So(evt.(*EventKey).Rune(), ShouldEqual, 'b')
+		})
+
+		Convey("drop the oldest events when full", func() {
+			small := NewEventQueue(2)
+			small.Push(NewEventKey(KeyRune, 'a', ModNone))
+			small.Push(NewEventResize(10, 10))
+			small.Push(NewEventInterrupt(nil))
+			small.Push(NewEventKey(KeyRune, 'b', ModNone))
+			So(small.Len(), ShouldEqual, 2)
+			So(small.Metrics().Dropped, ShouldEqual, 2)
+			evt, _ = small.TryPop()
+			So(evt, ShouldHaveSameTypeAs, &EventInterrupt{})
+			evt, _ = small.TryPop()
+			So(evt.(*EventKey).Rune(), ShouldEqual, 'b')
+		})
+
+		Convey("wait for events until closed", func() {
+			go func() {
+				time.Sleep(time.Millisecond * 10)
+				q.Push(NewEventKey(KeyRune, 'w', ModNone))
+			}()
+			evt = q.Pop()
+			So(evt.(*EventKey).Rune(), ShouldEqual, 'w')
+			go func() {
+				time.Sleep(time.Millisecond * 10)
+				q.Close()
+			}()
+			So(q.Pop(), ShouldBeNil)
+			So(q.IsClosed(), ShouldBeTrue)
+			q.Push(NewEventResize(1, 1))
+			So(q.Len(), ShouldEqual, 0)
+		})
+	})
+}
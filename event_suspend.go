@@ -0,0 +1,42 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"time"
+)
+
+// EventSuspend is sent when the process is asked to stop, with SIGTSTP, or
+// has been continued, with SIGCONT, by job control on the terminal the
+// display is running on.
+type EventSuspend struct {
+	suspend bool
+	t       time.Time
+}
+
+// When returns the time when this EventSuspend was created.
+func (ev *EventSuspend) When() time.Time {
+	return ev.t
+}
+
+// Suspend returns true if the process is asked to stop, false if continued.
+func (ev *EventSuspend) Suspend() bool {
+	return ev.suspend
+}
+
+// NewEventSuspend returns a new EventSuspend.
+func NewEventSuspend(suspend bool) *EventSuspend {
+	return &EventSuspend{t: time.Now(), suspend: suspend}
+}
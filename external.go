@@ -0,0 +1,126 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// externalRunner is implemented by displays on a tty that other programs can
+// be run upon, see cDisplay
+type externalRunner interface {
+	externalTty() *os.File
+	holdInput()
+	releaseInput()
+}
+
+// RunExternal runs the given command, an editor or pager for instance, upon
+// the terminal the display is on and waits for it to exit.  The terminal is
+// handed over as when suspended, see Suspend, though SignalSuspend is not
+// emitted: the alternate screen is left, mouse, paste and focus reporting are
+// turned off and the terminal modes are restored.  The display stops reading
+// input, and events are not processed, until the command exits.  The terminal
+// is then taken back and the whole display redrawn, the display itself is
+// kept, along with its cursor, title and modes, unlike ReleaseDisplay and
+// CaptureDisplay.
+//
+// Any of the Stdin, Stdout and Stderr of the command that are nil are set to
+// the tty.  Returns ErrNoTty if the display is not on a tty, such as offscreen
+// displays and those of SSH sessions, otherwise the error running the command
+// if any.
+func (d *CDisplayManager) RunExternal(cmd *exec.Cmd) error {
+	d.Lock()
+	display := d.display
+	d.Unlock()
+	if display == nil {
+		return ErrNoDisplay
+	}
+	unwrapped := display
+	if r, ok := display.(RecordingDisplay); ok {
+		unwrapped = r.Unwrap()
+	}
+	runner, ok := unwrapped.(externalRunner)
+	if !ok || runner.externalTty() == nil {
+		return ErrNoTty
+	}
+	tty := runner.externalTty()
+	d.suspendLock.Lock()
+	if d.suspended {
+		d.suspendLock.Unlock()
+		return fmt.Errorf("display suspended")
+	}
+	d.suspended = true
+	done := make(chan struct{})
+	d.external = done
+	d.suspendLock.Unlock()
+	defer func() {
+		d.suspendLock.Lock()
+		d.external = nil
+		d.suspendLock.Unlock()
+		close(done)
+	}()
+
+	d.breakPointerGrab()
+	runner.holdInput()
+	defer runner.releaseInput()
+	if err := display.Suspend(); err != nil {
+		d.displayResumed()
+		return err
+	}
+	if cmd.Stdin == nil {
+		cmd.Stdin = tty
+	}
+	if cmd.Stdout == nil {
+		cmd.Stdout = tty
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = tty
+	}
+	d.LogTrace("running external command: %v", cmd.Args)
+	err := cmd.Run()
+	if rerr := display.Resume(); rerr != nil {
+		d.LogErr(rerr)
+	}
+	d.displayResumed()
+	d.LogTrace("external command finished: %v", cmd.Args)
+	return err
+}
+
+// holdForExternal returns true if the event given has been taken care of
+// while an external program is running, see RunExternal, otherwise waits for
+// the program to exit before returning false
+func (d *CDisplayManager) holdForExternal(evt Event) bool {
+	d.suspendLock.Lock()
+	done := d.external
+	d.suspendLock.Unlock()
+	if done == nil {
+		return false
+	}
+	if e, ok := evt.(*EventSuspend); ok {
+		// the program is stopped by job control, stopping the process as
+		// well hands the terminal back to the shell until continued
+		if job := d.jobControl(); job != nil && e.Suspend() {
+			job.stopJob()
+		}
+		return true
+	}
+	select {
+	case <-done:
+	case <-d.stopping:
+	}
+	return false
+}
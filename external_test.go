@@ -0,0 +1,33 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"os/exec"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDisplayManagerRunExternal(t *testing.T) {
+	Convey("Running an external program", t, func() {
+		d := NewDisplayManager("external", OffscreenDisplayTtyPath)
+		defer d.Destroy()
+		So(d.RunExternal(exec.Command("true")), ShouldEqual, ErrNoDisplay)
+		d.CaptureDisplay(OffscreenDisplayTtyPath)
+		So(d.RunExternal(exec.Command("true")), ShouldEqual, ErrNoTty)
+		So(d.IsSuspended(), ShouldBeFalse)
+	})
+}
@@ -0,0 +1,198 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"fmt"
+	"time"
+)
+
+var (
+	// DefaultFrameRate is the most frames per second a display manager
+	// renders, unless changed with SetFrameRate
+	DefaultFrameRate = 60
+)
+
+const (
+	SignalFrame Signal = "frame"
+)
+
+// FrameStats describes the rendering of one frame, these are emitted with
+// SignalFrame after each frame is rendered.
+type FrameStats struct {
+	// number of the frame, starting from one
+	Frame uint64
+	// when rendering the frame started
+	Start time.Time
+	// the draw, show and sync requests merged into this frame
+	Requests ScreenStateReq
+	// number of requests merged into this frame
+	Merged int
+	// time taken to draw the window onto the display
+	Draw time.Duration
+	// time taken to show, or sync, the display
+	Show time.Duration
+	// time taken to render the frame
+	Total time.Duration
+}
+
+func (f FrameStats) String() string {
+	return fmt.Sprintf(
+		"{frame:%v,merged:%v,draw:%v,show:%v,total:%v}",
+		f.Frame, f.Merged, f.Draw, f.Show, f.Total,
+	)
+}
+
+// SetFrameRate sets the most frames per second rendered, any draw, show and
+// sync requests made between frames are merged into the next frame.  A rate
+// of zero or less renders requests as soon as they are made.
+func (d *CDisplayManager) SetFrameRate(fps int) {
+	d.frameLock.Lock()
+	defer d.frameLock.Unlock()
+	d.frameRate = fps
+}
+
+func (d *CDisplayManager) GetFrameRate() (fps int) {
+	d.frameLock.Lock()
+	defer d.frameLock.Unlock()
+	return d.frameRate
+}
+
+// SetRenderDirtyOnly sets whether frames are only rendered when requested,
+// the default, or continuously at the frame rate for things like animations.
+// When rendering continuously without a frame rate, DefaultFrameRate is used.
+func (d *CDisplayManager) SetRenderDirtyOnly(dirtyOnly bool) {
+	d.frameLock.Lock()
+	d.dirtyOnly = dirtyOnly
+	d.frameLock.Unlock()
+	if !dirtyOnly && d.IsRunning() {
+		// wake the scheduler to start rendering, requests are merged so
+		// there is no need to wait if others are pending already
+		select {
+		case d.requests <- NullRequest:
+		default:
+		}
+	}
+}
+
+// drawWaker is implemented by display managers that can be asked to draw
+// without waiting, so that windows may ask while being drawn
+type drawWaker interface {
+	wakeDraw()
+}
+
+// wakeDraw requests a draw without waiting for the screen request worker.
+// When the requests are queued to capacity, the worker has frames to render
+// still and the draw is flagged for the next of them instead.
+func (d *CDisplayManager) wakeDraw() {
+	if !d.IsRunning() {
+		return
+	}
+	select {
+	case d.requests <- DrawRequest:
+	default:
+		d.frameLock.Lock()
+		d.drawNext = true
+		d.frameLock.Unlock()
+	}
+}
+
+// takeDrawNext returns true, and clears the flag, if a draw was flagged for
+// the next frame by wakeDraw
+func (d *CDisplayManager) takeDrawNext() (draw bool) {
+	d.frameLock.Lock()
+	defer d.frameLock.Unlock()
+	draw, d.drawNext = d.drawNext, false
+	return
+}
+
+func (d *CDisplayManager) IsRenderDirtyOnly() bool {
+	d.frameLock.Lock()
+	defer d.frameLock.Unlock()
+	return d.dirtyOnly
+}
+
+// LastFrameStats returns the details of the last frame rendered
+func (d *CDisplayManager) LastFrameStats() FrameStats {
+	d.frameLock.Lock()
+	defer d.frameLock.Unlock()
+	return d.frameStats
+}
+
+// frameInterval returns the least time between the start of one frame and
+// the next
+func (d *CDisplayManager) frameInterval() time.Duration {
+	d.frameLock.Lock()
+	defer d.frameLock.Unlock()
+	fps := d.frameRate
+	if fps <= 0 && !d.dirtyOnly {
+		fps = DefaultFrameRate
+	}
+	if fps <= 0 {
+		return 0
+	}
+	return time.Second / time.Duration(fps)
+}
+
+// nextFrameDelay returns how long to wait before rendering the next frame
+func (d *CDisplayManager) nextFrameDelay() time.Duration {
+	interval := d.frameInterval()
+	d.frameLock.Lock()
+	last := d.frameStats.Start
+	d.frameLock.Unlock()
+	if interval <= 0 || last.IsZero() {
+		return 0
+	}
+	if delay := time.Until(last.Add(interval)); delay > 0 {
+		return delay
+	}
+	return 0
+}
+
+// renderFrame draws, shows and syncs the display as requested, once
+func (d *CDisplayManager) renderFrame(requests ScreenStateReq, merged int) {
+	d.Lock()
+	display, waiting := d.display, d.waiting
+	d.Unlock()
+	if display == nil || waiting || d.IsSuspended() {
+		return
+	}
+	continuous := !d.IsRenderDirtyOnly()
+	if !continuous && requests&(DrawRequest|ShowRequest|SyncRequest) == 0 {
+		return
+	}
+	stats := FrameStats{
+		Start:    time.Now(),
+		Requests: requests,
+		Merged:   merged,
+	}
+	if continuous || requests&DrawRequest != 0 {
+		d.DrawScreen()
+	}
+	stats.Draw = time.Since(stats.Start)
+	if requests&SyncRequest != 0 {
+		display.Sync()
+	} else if continuous || requests&(DrawRequest|ShowRequest) != 0 {
+		display.Show()
+	}
+	stats.Total = time.Since(stats.Start)
+	stats.Show = stats.Total - stats.Draw
+	d.frameLock.Lock()
+	d.frameCount++
+	stats.Frame = d.frameCount
+	d.frameStats = stats
+	d.frameLock.Unlock()
+	d.Emit(SignalFrame, d, stats)
+}
@@ -0,0 +1,163 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFrameScheduler(t *testing.T) {
+	Convey("Scheduling frames", t, func() {
+		d := NewDisplayManager("frames", OffscreenDisplayTtyPath)
+		defer d.Destroy()
+		So(d.GetFrameRate(), ShouldEqual, DefaultFrameRate)
+		So(d.IsRenderDirtyOnly(), ShouldBeTrue)
+		d.CaptureDisplay(OffscreenDisplayTtyPath)
+		win := NewWindow("frames", d)
+		d.SetActiveWindow(win)
+
+		var lock sync.Mutex
+		var frames []FrameStats
+		var onFrame, onDraw func()
+		win.Connect(SignalDraw, "frame-draw-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+			lock.Lock()
+			fn := onDraw
+			lock.Unlock()
+			if fn != nil {
+				fn()
+			}
+			return EVENT_PASS
+		})
+		// listeners are connected before running, signals are not locked
+		d.Connect(SignalFrame, "frame-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+			if stats, ok := argv[1].(FrameStats); ok {
+				lock.Lock()
+				frames = append(frames, stats)
+				fn := onFrame
+				lock.Unlock()
+				if fn != nil {
+					fn()
+				}
+			}
+			return EVENT_PASS
+		})
+		countFrames := func() int {
+			lock.Lock()
+			defer lock.Unlock()
+			return len(frames)
+		}
+
+		stopped := make(chan struct{})
+		go func() {
+			_ = d.Run()
+			close(stopped)
+		}()
+		defer func() {
+			d.RequestQuit()
+			<-stopped
+		}()
+		// frames are not rendered until the display has settled
+		isWaiting := func() bool {
+			d.Lock()
+			defer d.Unlock()
+			return d.waiting
+		}
+		deadline := time.Now().Add(time.Second)
+		for isWaiting() && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond * 5)
+		}
+		So(isWaiting(), ShouldBeFalse)
+
+		Convey("merging requests made between frames", func() {
+			d.SetFrameRate(10)
+			time.Sleep(time.Millisecond * 110)
+			before := countFrames()
+			for i := 0; i < 10; i++ {
+				d.RequestDraw()
+				d.RequestShow()
+			}
+			time.Sleep(time.Millisecond * 250)
+			after := countFrames()
+			So(after-before, ShouldBeBetweenOrEqual, 1, 3)
+			last := d.LastFrameStats()
+			So(last.Frame, ShouldEqual, uint64(after))
+			So(last.Requests&DrawRequest, ShouldNotEqual, 0)
+			So(last.Total, ShouldBeGreaterThanOrEqualTo, last.Draw)
+			merged := 0
+			lock.Lock()
+			for _, f := range frames[before:] {
+				merged += f.Merged
+			}
+			lock.Unlock()
+			So(merged, ShouldEqual, 20)
+			// nothing is rendered until requested
+			time.Sleep(time.Millisecond * 150)
+			So(countFrames(), ShouldEqual, after)
+		})
+
+		Convey("rendering continuously", func() {
+			d.SetFrameRate(50)
+			before := countFrames()
+			d.SetRenderDirtyOnly(false)
+			time.Sleep(time.Millisecond * 200)
+			d.SetRenderDirtyOnly(true)
+			So(countFrames()-before, ShouldBeBetweenOrEqual, 5, 12)
+		})
+
+		Convey("rendering continuously from a frame listener", func() {
+			d.SetFrameRate(50)
+			var once sync.Once
+			lock.Lock()
+			onFrame = func() {
+				once.Do(func() {
+					// more than the requests queued fit, none may block
+					for i := 0; i <= DisplayCallQueueCapacity; i++ {
+						d.SetRenderDirtyOnly(false)
+					}
+				})
+			}
+			lock.Unlock()
+			before := countFrames()
+			d.RequestDraw()
+			time.Sleep(time.Millisecond * 200)
+			d.SetRenderDirtyOnly(true)
+			So(countFrames()-before, ShouldBeGreaterThan, 3)
+		})
+
+		Convey("titling a window while it is drawn", func() {
+			var once sync.Once
+			lock.Lock()
+			onDraw = func() {
+				once.Do(func() {
+					// more than the requests queued fit, none may block
+					for i := 0; i <= DisplayCallQueueCapacity; i++ {
+						win.SetTitle(fmt.Sprintf("frame %d", i))
+					}
+				})
+			}
+			lock.Unlock()
+			before := countFrames()
+			d.RequestDraw()
+			time.Sleep(time.Millisecond * 200)
+			So(countFrames()-before, ShouldBeGreaterThan, 1)
+			So(win.GetTitle(), ShouldEqual, fmt.Sprintf("frame %d", DisplayCallQueueCapacity))
+		})
+	})
+}
@@ -0,0 +1,427 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// SignalAccelAction is emitted by windows and display managers when a
+	// key binding of their KeyMap is typed, with the name of the action and
+	// the KeyBinding typed
+	SignalAccelAction Signal = "accel-action"
+)
+
+var (
+	// DefaultChordTimeout is the most time allowed between the keys of a
+	// chord, such as Ctrl+X Ctrl+S, after which the keys typed so far are
+	// forgotten
+	DefaultChordTimeout = time.Second
+)
+
+// KeyStroke is a key pressed with modifiers, as reported by an EventKey
+type KeyStroke struct {
+	Key  Key
+	Rune rune
+	Mod  ModMask
+}
+
+// MakeKeyStroke returns the KeyStroke of the key given, normalized such that
+// it compares equal to the stroke of the EventKey reporting the same key.
+// Ctrl+H, Ctrl+I, Ctrl+[ and Ctrl+M are kept apart from Ctrl+Backspace,
+// Ctrl+Tab, Ctrl+Esc and Ctrl+Enter, as the kitty keyboard protocol reports
+// them, other terminals report them as Backspace, Tab, Esc and Enter.
+func MakeKeyStroke(key Key, r rune, mod ModMask) KeyStroke {
+	s := KeyStroke{Key: key, Rune: r, Mod: mod}
+	if s.Key == KeyRune && s.Mod&ModCtrl != 0 {
+		switch k, ok := controlKey(unicode.ToLower(s.Rune)); {
+		case !ok:
+		case k == KeyBackspace, k == KeyTab, k == KeyEsc, k == KeyEnter:
+			s.Rune = unicode.ToLower(s.Rune)
+		default:
+			s.Key = k
+		}
+	}
+	switch {
+	case s.Key == KeyRune:
+		// the shift is given by the rune itself
+		s.Mod &^= ModShift
+	case s.Key == KeyBacktab:
+		s.Mod &^= ModShift
+		s.Rune = 0
+	case s.Key < ' ':
+		switch s.Key {
+		case KeyBackspace, KeyTab, KeyEsc, KeyEnter:
+			// these keys are directly typeable without CTRL
+		default:
+			s.Mod |= ModCtrl
+		}
+		s.Rune = 0
+	default:
+		s.Rune = 0
+	}
+	return s
+}
+
+// KeyStrokeOf returns the KeyStroke of the key event given.
+func KeyStrokeOf(ev *EventKey) KeyStroke {
+	return MakeKeyStroke(ev.Key(), ev.Rune(), ev.Modifiers())
+}
+
+// String returns the name of the key stroke, as returned by EventKey.Name,
+// which ParseKeyBinding accepts.
+func (s KeyStroke) String() string {
+	r := s.Rune
+	if s.Key != KeyRune && s.Key < ' ' {
+		r = rune(s.Key)
+	}
+	return NewEventKeyType(s.Key, r, s.Mod, KeyEventPress).Name()
+}
+
+// KeyBinding is a sequence of one or more key strokes, those of more than
+// one are chords, such as Ctrl+X Ctrl+S.
+type KeyBinding []KeyStroke
+
+// String returns the names of the key strokes, separated by spaces.
+func (b KeyBinding) String() string {
+	names := make([]string, len(b))
+	for i, s := range b {
+		names[i] = s.String()
+	}
+	return strings.Join(names, " ")
+}
+
+// Equals returns true if the given binding has the same key strokes.
+func (b KeyBinding) Equals(other KeyBinding) bool {
+	if len(b) != len(other) {
+		return false
+	}
+	for i := range b {
+		if b[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	keyBindingModifiers = []struct {
+		name string
+		mod  ModMask
+	}{
+		{"shift", ModShift},
+		{"ctrl", ModCtrl},
+		{"control", ModCtrl},
+		{"alt", ModAlt},
+		{"meta", ModMeta},
+		{"super", ModSuper},
+		{"hyper", ModHyper},
+	}
+	keyBindingAliases = map[string]KeyStroke{
+		"space":    {Key: KeyRune, Rune: ' '},
+		"escape":   {Key: KeyEsc},
+		"return":   {Key: KeyEnter},
+		"pageup":   {Key: KeyPgUp},
+		"pagedown": {Key: KeyPgDn},
+	}
+	keyBindingNames     map[string]Key
+	keyBindingNamesOnce sync.Once
+)
+
+// ParseKeyBinding parses the names of one or more key strokes, separated by
+// spaces, such as "Ctrl+Shift+F5" or "Ctrl+X Ctrl+S".  Each key stroke is
+// any of the modifiers Shift, Ctrl, Alt, Meta, Super and Hyper followed by +
+// or -, and then the name of a key in KeyNames or a single character.  The
+// names are not case sensitive, and those returned by EventKey.Name are
+// accepted, such as "Alt+Rune[x]".
+func ParseKeyBinding(binding string) (KeyBinding, error) {
+	fields := strings.Fields(binding)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty key binding")
+	}
+	kb := make(KeyBinding, 0, len(fields))
+	for _, field := range fields {
+		stroke, err := parseKeyStroke(field)
+		if err != nil {
+			return nil, err
+		}
+		kb = append(kb, stroke)
+	}
+	return kb, nil
+}
+
+func parseKeyStroke(text string) (KeyStroke, error) {
+	rest := text
+	mod := ModNone
+	for stripped := true; stripped; {
+		stripped = false
+		lower := strings.ToLower(rest)
+		for _, m := range keyBindingModifiers {
+			n := len(m.name)
+			if len(lower) > n+1 && strings.HasPrefix(lower, m.name) && (lower[n] == '+' || lower[n] == '-') {
+				mod |= m.mod
+				rest = rest[n+1:]
+				stripped = true
+				break
+			}
+		}
+	}
+	keyBindingNamesOnce.Do(func() {
+		keyBindingNames = make(map[string]Key, len(KeyNames))
+		for k, name := range KeyNames {
+			keyBindingNames[strings.ToLower(name)] = k
+		}
+	})
+	lower := strings.ToLower(rest)
+	if k, ok := keyBindingNames[lower]; ok {
+		return MakeKeyStroke(k, rune(k), mod), nil
+	}
+	if s, ok := keyBindingAliases[lower]; ok {
+		return MakeKeyStroke(s.Key, s.Rune, mod), nil
+	}
+	if strings.HasPrefix(lower, "rune[") && strings.HasSuffix(rest, "]") {
+		if r, size := utf8.DecodeRuneInString(rest[5:]); size > 0 && 5+size == len(rest)-1 {
+			return MakeKeyStroke(KeyRune, r, mod), nil
+		}
+	}
+	if strings.HasPrefix(lower, "key[") && strings.HasSuffix(rest, "]") {
+		if values := strings.Split(rest[4:len(rest)-1], ","); len(values) == 2 {
+			k, kerr := strconv.Atoi(values[0])
+			r, rerr := strconv.Atoi(values[1])
+			if kerr == nil && rerr == nil {
+				return MakeKeyStroke(Key(k), rune(r), mod), nil
+			}
+		}
+	}
+	if utf8.RuneCountInString(rest) == 1 {
+		r, _ := utf8.DecodeRuneInString(rest)
+		return MakeKeyStroke(KeyRune, r, mod), nil
+	}
+	return KeyStroke{}, fmt.Errorf("unknown key %q in key binding %q", rest, text)
+}
+
+// KeyMap binds key strokes and chords of key strokes to the names of
+// actions.  When one is typed, the window or display manager the KeyMap is
+// attached to emits SignalAccelAction with the name of the action.
+type KeyMap interface {
+	Bind(binding string, action string) error
+	BindKeys(binding KeyBinding, action string)
+	Unbind(binding KeyBinding)
+	Clear()
+	Lookup(binding KeyBinding) (action string, found bool, prefix bool)
+	Bindings() []KeyBinding
+	Len() int
+}
+
+type keyMapEntry struct {
+	binding KeyBinding
+	action  string
+}
+
+// CKeyMap is the standard KeyMap, safe for concurrent use
+type CKeyMap struct {
+	bindings map[string]keyMapEntry
+	prefixes map[string]int
+
+	sync.RWMutex
+}
+
+func NewKeyMap() *CKeyMap {
+	return &CKeyMap{
+		bindings: make(map[string]keyMapEntry),
+		prefixes: make(map[string]int),
+	}
+}
+
+// Bind parses the given key binding, see ParseKeyBinding, and binds it to
+// the action given, replacing the action bound before, if any.
+func (m *CKeyMap) Bind(binding string, action string) error {
+	kb, err := ParseKeyBinding(binding)
+	if err != nil {
+		return err
+	}
+	m.BindKeys(kb, action)
+	return nil
+}
+
+// BindKeys binds the given key binding to the action given, replacing the
+// action bound before, if any.
+func (m *CKeyMap) BindKeys(binding KeyBinding, action string) {
+	if len(binding) == 0 {
+		return
+	}
+	m.Lock()
+	defer m.Unlock()
+	key := binding.String()
+	if _, ok := m.bindings[key]; !ok {
+		for i := 1; i < len(binding); i++ {
+			m.prefixes[binding[:i].String()]++
+		}
+	}
+	m.bindings[key] = keyMapEntry{binding: binding, action: action}
+}
+
+// Unbind removes the given key binding, if bound.
+func (m *CKeyMap) Unbind(binding KeyBinding) {
+	m.Lock()
+	defer m.Unlock()
+	key := binding.String()
+	if _, ok := m.bindings[key]; !ok {
+		return
+	}
+	delete(m.bindings, key)
+	for i := 1; i < len(binding); i++ {
+		prefix := binding[:i].String()
+		if m.prefixes[prefix]--; m.prefixes[prefix] <= 0 {
+			delete(m.prefixes, prefix)
+		}
+	}
+}
+
+// Clear removes all key bindings.
+func (m *CKeyMap) Clear() {
+	m.Lock()
+	defer m.Unlock()
+	m.bindings = make(map[string]keyMapEntry)
+	m.prefixes = make(map[string]int)
+}
+
+// Lookup returns the action bound to the given key binding, if found, and
+// whether the key binding is the start of longer key bindings.
+func (m *CKeyMap) Lookup(binding KeyBinding) (action string, found bool, prefix bool) {
+	m.RLock()
+	defer m.RUnlock()
+	key := binding.String()
+	entry, found := m.bindings[key]
+	return entry.action, found, m.prefixes[key] > 0
+}
+
+// Bindings returns the key bindings bound, sorted by their names.
+func (m *CKeyMap) Bindings() []KeyBinding {
+	m.RLock()
+	defer m.RUnlock()
+	keys := make([]string, 0, len(m.bindings))
+	for key := range m.bindings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	bindings := make([]KeyBinding, len(keys))
+	for i, key := range keys {
+		bindings[i] = m.bindings[key].binding
+	}
+	return bindings
+}
+
+func (m *CKeyMap) Len() int {
+	m.RLock()
+	defer m.RUnlock()
+	return len(m.bindings)
+}
+
+// GetKeyMap returns the key bindings of the display manager, which apply
+// to all of its windows.  The key bindings of the active window take
+// precedence over those of the display manager.
+func (d *CDisplayManager) GetKeyMap() KeyMap {
+	d.chordLock.Lock()
+	defer d.chordLock.Unlock()
+	return d.keyMap
+}
+
+func (d *CDisplayManager) SetKeyMap(keyMap KeyMap) {
+	d.chordLock.Lock()
+	defer d.chordLock.Unlock()
+	d.keyMap = keyMap
+	d.chord = nil
+}
+
+// SetChordTimeout sets the most time allowed between the keys of a chord.
+// A timeout of zero or less uses DefaultChordTimeout.
+func (d *CDisplayManager) SetChordTimeout(timeout time.Duration) {
+	d.chordLock.Lock()
+	defer d.chordLock.Unlock()
+	if timeout <= 0 {
+		timeout = DefaultChordTimeout
+	}
+	d.chordTimeout = timeout
+}
+
+func (d *CDisplayManager) GetChordTimeout() time.Duration {
+	d.chordLock.Lock()
+	defer d.chordLock.Unlock()
+	return d.chordTimeout
+}
+
+// processAccel looks up the key typed, following any typed before it as
+// part of a chord, in the key bindings of the active window and then those
+// of the display manager.  Keys that start or continue a chord are consumed,
+// as are those completing a binding for which the action is handled.  A key
+// that breaks a chord is looked up on its own.
+func (d *CDisplayManager) processAccel(w Window, e *EventKey) EventFlag {
+	if e.EventType() == KeyEventRelease {
+		return EVENT_PASS
+	}
+	d.chordLock.Lock()
+	if len(d.chord) > 0 && time.Since(d.chordAt) > d.chordTimeout {
+		d.LogTrace("chord timed out: %v", d.chord)
+		d.chord = nil
+	}
+	stroke := KeyStrokeOf(e)
+	pending := append(append(KeyBinding{}, d.chord...), stroke)
+	type owner struct {
+		object Object
+		keyMap KeyMap
+	}
+	owners := make([]owner, 0, 2)
+	if w != nil {
+		if keyMap := windowKeyMap(w); keyMap != nil {
+			owners = append(owners, owner{w, keyMap})
+		}
+	}
+	if d.keyMap != nil {
+		owners = append(owners, owner{d, d.keyMap})
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		for _, o := range owners {
+			action, found, prefix := o.keyMap.Lookup(pending)
+			if found {
+				d.chord = nil
+				d.chordLock.Unlock()
+				return o.object.Emit(SignalAccelAction, o.object, action, pending)
+			}
+			if prefix {
+				d.chord = pending
+				d.chordAt = time.Now()
+				d.chordLock.Unlock()
+				return EVENT_STOP
+			}
+		}
+		if len(pending) == 1 {
+			break
+		}
+		d.LogTrace("key binding not found: %v", pending)
+		pending = KeyBinding{stroke}
+	}
+	d.chord = nil
+	d.chordLock.Unlock()
+	return EVENT_PASS
+}
@@ -0,0 +1,441 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	toml "github.com/pelletier/go-toml"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// KeyMapError is an error found in a key map file, at the line and column
+// given, both of which start at one.
+type KeyMapError struct {
+	Path    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *KeyMapError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.Path, e.Line, e.Column, e.Message)
+}
+
+// KeyMapErrors are all of the invalid entries found in a key map file.
+type KeyMapErrors []*KeyMapError
+
+func (e KeyMapErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// KeyMapEntry is one key binding of a key map file.  An empty action
+// removes the key binding instead.
+type KeyMapEntry struct {
+	Binding KeyBinding
+	Action  string
+	Line    int
+	Column  int
+}
+
+// KeyMapConflict describes a key binding of a key map file that does not
+// work as written, either because it is given more than once or because it
+// shadows, or is shadowed by, a chord.
+type KeyMapConflict struct {
+	Path    string
+	Line    int
+	Column  int
+	Binding KeyBinding
+	Message string
+}
+
+func (c KeyMapConflict) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", c.Path, c.Line, c.Column, c.Message)
+}
+
+// KeyMapFile is a parsed key map file, a JSON object of key bindings, in the
+// names EventKey.Name() produces, to the names of actions:
+//
+//	{
+//	    "Ctrl+X Ctrl+S": "save",
+//	    "Ctrl+Q": "quit",
+//	    "F1": ""
+//	}
+//
+// An empty action, or null, removes a key binding of the application.  Key
+// map files named with a .yaml or .yml extension are read as a YAML mapping
+// instead, and those with a .toml extension as TOML, where the key bindings
+// are quoted keys and there is no null:
+//
+//	"Ctrl+X Ctrl+S" = "save"
+//	"F1" = ""
+type KeyMapFile struct {
+	Path    string
+	Entries []KeyMapEntry
+}
+
+// LoadKeyMapFile reads and validates the key map file at the path given.
+func LoadKeyMapFile(path string) (*KeyMapFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadKeyMap(path, f)
+}
+
+// LoadKeyMap reads and validates a key map, see KeyMapFile, named path in
+// the errors returned.  The format is chosen by the extension of the path,
+// JSON unless YAML or TOML.  Syntax errors are returned as a *KeyMapError,
+// and invalid entries as KeyMapErrors listing all of them.
+func LoadKeyMap(path string, in io.Reader) (*KeyMapFile, error) {
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return loadYamlKeyMap(path, data)
+	case ".toml":
+		return loadTomlKeyMap(path, data)
+	}
+	return loadJsonKeyMap(path, data)
+}
+
+// loadJsonKeyMap parses a key map written in JSON
+func loadJsonKeyMap(path string, data []byte) (*KeyMapFile, error) {
+	file := &KeyMapFile{Path: path}
+	errorAt := func(offset int64, format string, argv ...interface{}) *KeyMapError {
+		line, column := textPosition(data, offset)
+		return &KeyMapError{Path: path, Line: line, Column: column, Message: fmt.Sprintf(format, argv...)}
+	}
+	syntaxError := func(err error) error {
+		if se, ok := err.(*json.SyntaxError); ok {
+			return errorAt(se.Offset, "%v", se)
+		}
+		if err == io.EOF {
+			return errorAt(int64(len(data)), "unexpected end of key map")
+		}
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil {
+		return nil, syntaxError(err)
+	} else if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, errorAt(skipJsonSpace(data, 0), "expected an object of key bindings to actions")
+	}
+	var invalid KeyMapErrors
+	for dec.More() {
+		keyAt := skipJsonSpace(data, dec.InputOffset())
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, syntaxError(err)
+		}
+		text, _ := tok.(string)
+		valueAt := skipJsonSpace(data, dec.InputOffset())
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, syntaxError(err)
+		}
+		var action *string
+		if err := json.Unmarshal(raw, &action); err != nil {
+			invalid = append(invalid, errorAt(valueAt, "action of %q is not a string: %s", text, raw))
+			continue
+		}
+		binding, err := ParseKeyBinding(text)
+		if err != nil {
+			invalid = append(invalid, errorAt(keyAt, "%v", err))
+			continue
+		}
+		line, column := textPosition(data, keyAt)
+		entry := KeyMapEntry{Binding: binding, Line: line, Column: column}
+		if action != nil {
+			entry.Action = *action
+		}
+		file.Entries = append(file.Entries, entry)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, syntaxError(err)
+	}
+	if len(invalid) > 0 {
+		return nil, invalid
+	}
+	return file, nil
+}
+
+var (
+	yamlErrorLine = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+	tomlErrorAt   = regexp.MustCompile(`^\((\d+), (\d+)\): (.*)$`)
+)
+
+// loadYamlKeyMap parses a key map written in YAML, a mapping of key bindings
+// to actions
+func loadYamlKeyMap(path string, data []byte) (*KeyMapFile, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		if m := yamlErrorLine.FindStringSubmatch(err.Error()); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			return nil, &KeyMapError{Path: path, Line: line, Column: 1, Message: m[2]}
+		}
+		return nil, &KeyMapError{Path: path, Line: 1, Column: 1, Message: err.Error()}
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		line, column := 1, 1
+		if len(doc.Content) > 0 {
+			line, column = doc.Content[0].Line, doc.Content[0].Column
+		}
+		return nil, &KeyMapError{Path: path, Line: line, Column: column, Message: "expected a mapping of key bindings to actions"}
+	}
+	file := &KeyMapFile{Path: path}
+	var invalid KeyMapErrors
+	errorAt := func(node *yaml.Node, format string, argv ...interface{}) *KeyMapError {
+		return &KeyMapError{Path: path, Line: node.Line, Column: node.Column, Message: fmt.Sprintf(format, argv...)}
+	}
+	mapping := doc.Content[0]
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+		entry := KeyMapEntry{Line: key.Line, Column: key.Column}
+		switch {
+		case value.Kind == yaml.ScalarNode && value.Tag == "!!null":
+		case value.Kind == yaml.ScalarNode && value.Tag == "!!str":
+			entry.Action = value.Value
+		default:
+			invalid = append(invalid, errorAt(value, "action of %q is not a string: %s", key.Value, value.Value))
+			continue
+		}
+		binding, err := ParseKeyBinding(key.Value)
+		if err != nil {
+			invalid = append(invalid, errorAt(key, "%v", err))
+			continue
+		}
+		entry.Binding = binding
+		file.Entries = append(file.Entries, entry)
+	}
+	if len(invalid) > 0 {
+		return nil, invalid
+	}
+	return file, nil
+}
+
+// loadTomlKeyMap parses a key map written in TOML, a table of key bindings
+// to actions
+func loadTomlKeyMap(path string, data []byte) (*KeyMapFile, error) {
+	tree, err := toml.LoadBytes(data)
+	if err != nil {
+		if m := tomlErrorAt.FindStringSubmatch(err.Error()); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			column, _ := strconv.Atoi(m[2])
+			return nil, &KeyMapError{Path: path, Line: line, Column: column, Message: m[3]}
+		}
+		return nil, &KeyMapError{Path: path, Line: 1, Column: 1, Message: err.Error()}
+	}
+	file := &KeyMapFile{Path: path}
+	var invalid KeyMapErrors
+	for _, key := range tree.Keys() {
+		at := tree.GetPositionPath([]string{key})
+		errorAt := func(format string, argv ...interface{}) *KeyMapError {
+			return &KeyMapError{Path: path, Line: at.Line, Column: at.Col, Message: fmt.Sprintf(format, argv...)}
+		}
+		action, ok := tree.GetPath([]string{key}).(string)
+		if !ok {
+			invalid = append(invalid, errorAt("action of %q is not a string", key))
+			continue
+		}
+		binding, err := ParseKeyBinding(key)
+		if err != nil {
+			invalid = append(invalid, errorAt("%v", err))
+			continue
+		}
+		file.Entries = append(file.Entries, KeyMapEntry{Binding: binding, Action: action, Line: at.Line, Column: at.Col})
+	}
+	// the keys of a table are not kept in order, one key per line
+	sort.Slice(invalid, func(i, j int) bool { return invalid[i].Line < invalid[j].Line })
+	sort.Slice(file.Entries, func(i, j int) bool { return file.Entries[i].Line < file.Entries[j].Line })
+	if len(invalid) > 0 {
+		return nil, invalid
+	}
+	return file, nil
+}
+
+// Conflicts returns the entries that do not work as written, those given
+// more than once, where the last one is used, and chords that can never be
+// typed as a shorter key binding is bound to a start of them.  The bindings
+// of the KeyMap given, if not nil, are taken into account as well, as though
+// the entries were applied to it.
+func (f *KeyMapFile) Conflicts(keyMap KeyMap) (conflicts []KeyMapConflict) {
+	bound := make(map[string]string)
+	if keyMap != nil {
+		for _, kb := range keyMap.Bindings() {
+			action, _, _ := keyMap.Lookup(kb)
+			bound[kb.String()] = action
+		}
+	}
+	last := make(map[string]KeyMapEntry)
+	for _, entry := range f.Entries {
+		key := entry.Binding.String()
+		if prev, ok := last[key]; ok {
+			conflicts = append(conflicts, f.conflict(entry,
+				"%s is bound again, to %q instead of %q at line %d",
+				key, entry.Action, prev.Action, prev.Line,
+			))
+		}
+		last[key] = entry
+		if entry.Action == "" {
+			delete(bound, key)
+		} else {
+			bound[key] = entry.Action
+		}
+	}
+	for _, entry := range f.Entries {
+		key := entry.Binding.String()
+		if entry.Action == "" || last[key].Line != entry.Line || last[key].Column != entry.Column {
+			// removed, or replaced and reported already
+			continue
+		}
+		for i := 1; i < len(entry.Binding); i++ {
+			prefix := entry.Binding[:i].String()
+			if action, ok := bound[prefix]; ok {
+				conflicts = append(conflicts, f.conflict(entry,
+					"%s bound to %q can never be typed, %s is bound to %q",
+					key, entry.Action, prefix, action,
+				))
+				break
+			}
+		}
+		for other, action := range bound {
+			if strings.HasPrefix(other, key+" ") {
+				conflicts = append(conflicts, f.conflict(entry,
+					"%s bound to %q hides %s bound to %q",
+					key, entry.Action, other, action,
+				))
+			}
+		}
+	}
+	return
+}
+
+func (f *KeyMapFile) conflict(entry KeyMapEntry, format string, argv ...interface{}) KeyMapConflict {
+	return KeyMapConflict{
+		Path:    f.Path,
+		Line:    entry.Line,
+		Column:  entry.Column,
+		Binding: entry.Binding,
+		Message: fmt.Sprintf(format, argv...),
+	}
+}
+
+// Apply binds, or removes, the key bindings of the file in the KeyMap given,
+// in the order they are written.
+func (f *KeyMapFile) Apply(keyMap KeyMap) {
+	for _, entry := range f.Entries {
+		if entry.Action == "" {
+			keyMap.Unbind(entry.Binding)
+			continue
+		}
+		keyMap.BindKeys(entry.Binding, entry.Action)
+	}
+}
+
+// DumpKeyMap writes the key bindings of the KeyMap given in the format read
+// by LoadKeyMap, sorted by their names.
+func DumpKeyMap(keyMap KeyMap, out io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for i, kb := range keyMap.Bindings() {
+		action, _, _ := keyMap.Lookup(kb)
+		key, _ := json.Marshal(kb.String())
+		value, _ := json.Marshal(action)
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n    ")
+		buf.Write(key)
+		buf.WriteString(": ")
+		buf.Write(value)
+	}
+	buf.WriteString("\n}\n")
+	_, err := out.Write(buf.Bytes())
+	return err
+}
+
+// applyKeyMap applies the key map file given with --cdk-keymap to the key
+// bindings of the display manager, logging any conflicts, and writes the
+// effective key bindings to the path given with --cdk-keymap-dump
+func (app *CApp) applyKeyMap() error {
+	keyMap := app.display.GetKeyMap()
+	if keyMap == nil {
+		return nil
+	}
+	if app.keyMap != nil {
+		for _, conflict := range app.keyMap.Conflicts(keyMap) {
+			WarnF("key binding conflict: %v", conflict)
+		}
+		app.keyMap.Apply(keyMap)
+	}
+	if app.keyDump != "" {
+		f, err := os.Create(app.keyDump)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return DumpKeyMap(keyMap, f)
+	}
+	return nil
+}
+
+// skipJsonSpace returns the offset of the first byte at or after the offset
+// given that is not white space or a separator between JSON values
+func skipJsonSpace(data []byte, offset int64) int64 {
+	for offset < int64(len(data)) {
+		switch data[offset] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+// textPosition returns the line and column, in runes, of the byte offset
+// given within the text
+func textPosition(data []byte, offset int64) (line, column int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	if i := bytes.LastIndexByte(before, '\n'); i >= 0 {
+		before = before[i+1:]
+	}
+	column = utf8.RuneCount(before) + 1
+	return
+}
@@ -0,0 +1,150 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestKeyMapFile(t *testing.T) {
+	Convey("Loading key map files", t, func() {
+		file, err := LoadKeyMap("keys.json", strings.NewReader(`{
+    "ctrl-x ctrl-s": "save",
+    "Ctrl+Q": "quit",
+    "F1": null
+}`))
+		So(err, ShouldBeNil)
+		So(file.Entries, ShouldHaveLength, 3)
+		So(file.Entries[0].Binding.String(), ShouldEqual, "Ctrl+X Ctrl+S")
+		So(file.Entries[0].Line, ShouldEqual, 2)
+		So(file.Entries[0].Column, ShouldEqual, 5)
+		So(file.Entries[2].Action, ShouldEqual, "")
+
+		m := NewKeyMap()
+		So(m.Bind("F1", "help"), ShouldBeNil)
+		So(m.Bind("F2", "menu"), ShouldBeNil)
+		So(file.Conflicts(m), ShouldBeEmpty)
+		file.Apply(m)
+		So(m.Len(), ShouldEqual, 3)
+		action, found, _ := m.Lookup(file.Entries[1].Binding)
+		So(found, ShouldBeTrue)
+		So(action, ShouldEqual, "quit")
+		_, found, _ = m.Lookup(file.Entries[2].Binding)
+		So(found, ShouldBeFalse)
+
+		Convey("dumps the effective bindings", func() {
+			var buf bytes.Buffer
+			So(DumpKeyMap(m, &buf), ShouldBeNil)
+			So(buf.String(), ShouldEqual, "{\n    \"Ctrl+Q\": \"quit\",\n    \"Ctrl+X Ctrl+S\": \"save\",\n    \"F2\": \"menu\"\n}\n")
+			dumped, err := LoadKeyMap("dump.json", &buf)
+			So(err, ShouldBeNil)
+			So(dumped.Entries, ShouldHaveLength, 3)
+			So(dumped.Entries[1].Binding, ShouldResemble, file.Entries[0].Binding)
+		})
+
+		Convey("points to the line and column of errors", func() {
+			_, err := LoadKeyMap("bad.json", strings.NewReader("{\n  \"F1\": \"help\"\n  \"F2\": \"menu\"\n}"))
+			So(err, ShouldHaveSameTypeAs, &KeyMapError{})
+			So(err.(*KeyMapError).Line, ShouldEqual, 3)
+
+			_, err = LoadKeyMap("bad.json", strings.NewReader("{\n  \"F1\": \"help\",\n  \"Ctrl+Nope\": \"x\",\n  \"F2\": 2\n}"))
+			So(err, ShouldHaveSameTypeAs, KeyMapErrors{})
+			errs := err.(KeyMapErrors)
+			So(errs, ShouldHaveLength, 2)
+			So(errs[0].Line, ShouldEqual, 3)
+			So(errs[0].Column, ShouldEqual, 3)
+			So(errs[1].Line, ShouldEqual, 4)
+			So(errs[1].Column, ShouldEqual, 9)
+			So(errs[1].Error(), ShouldStartWith, "bad.json:4:9: ")
+
+			_, err = LoadKeyMap("bad.json", strings.NewReader(`["F1"]`))
+			So(err, ShouldNotBeNil)
+			_, err = LoadKeyMap("bad.json", strings.NewReader(`{"F1": "help"`))
+			So(err, ShouldNotBeNil)
+			_, err = LoadKeyMapFile("missing.yaml")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("reads YAML key maps", func() {
+			file, err := LoadKeyMap("keys.yaml", strings.NewReader("# keys\nCtrl+X Ctrl+S: save\n\"Ctrl+.\": repeat\nF1: ~\n"))
+			So(err, ShouldBeNil)
+			So(file.Entries, ShouldHaveLength, 3)
+			So(file.Entries[0].Action, ShouldEqual, "save")
+			So(file.Entries[0].Line, ShouldEqual, 2)
+			So(file.Entries[1].Action, ShouldEqual, "repeat")
+			So(file.Entries[2].Action, ShouldEqual, "")
+			So(file.Entries[2].Line, ShouldEqual, 4)
+
+			_, err = LoadKeyMap("bad.yml", strings.NewReader("F1: help\nCtrl+Nope: x\nF2: [menu]\n"))
+			So(err, ShouldHaveSameTypeAs, KeyMapErrors{})
+			errs := err.(KeyMapErrors)
+			So(errs, ShouldHaveLength, 2)
+			So(errs[0].Error(), ShouldStartWith, "bad.yml:2:1: ")
+			So(errs[1].Error(), ShouldStartWith, "bad.yml:3:5: ")
+
+			_, err = LoadKeyMap("bad.yaml", strings.NewReader("F1: help\nF2: menu: x\n"))
+			So(err, ShouldHaveSameTypeAs, &KeyMapError{})
+			So(err.(*KeyMapError).Line, ShouldEqual, 2)
+			_, err = LoadKeyMap("bad.yaml", strings.NewReader("- F1\n"))
+			So(err, ShouldHaveSameTypeAs, &KeyMapError{})
+		})
+
+		Convey("reads TOML key maps", func() {
+			file, err := LoadKeyMap("keys.toml", strings.NewReader("# keys\n\"Ctrl+X Ctrl+S\" = \"save\"\n\"Ctrl+.\" = \"repeat\"\nF1 = \"\"\n"))
+			So(err, ShouldBeNil)
+			So(file.Entries, ShouldHaveLength, 3)
+			So(file.Entries[0].Action, ShouldEqual, "save")
+			So(file.Entries[0].Line, ShouldEqual, 2)
+			So(file.Entries[1].Action, ShouldEqual, "repeat")
+			So(file.Entries[2].Action, ShouldEqual, "")
+			So(file.Entries[2].Line, ShouldEqual, 4)
+
+			_, err = LoadKeyMap("bad.toml", strings.NewReader("F1 = \"help\"\n\"Ctrl+Nope\" = \"x\"\n  F2 = 2\n"))
+			So(err, ShouldHaveSameTypeAs, KeyMapErrors{})
+			errs := err.(KeyMapErrors)
+			So(errs, ShouldHaveLength, 2)
+			So(errs[0].Error(), ShouldStartWith, "bad.toml:2:1: ")
+			So(errs[1].Error(), ShouldStartWith, "bad.toml:3:3: ")
+
+			_, err = LoadKeyMap("bad.toml", strings.NewReader("F1 = \"help\"\nF2 =\n"))
+			So(err, ShouldHaveSameTypeAs, &KeyMapError{})
+			So(err.(*KeyMapError).Line, ShouldEqual, 3)
+		})
+
+		Convey("reports conflicts", func() {
+			file, err := LoadKeyMap("keys.json", strings.NewReader(`{
+  "Ctrl+X": "cut",
+  "Ctrl+X Ctrl+C": "quit",
+  "F3 x": "find",
+  "ctrl+q": "quit",
+  "Ctrl-Q": "exit"
+}`))
+			So(err, ShouldBeNil)
+			m := NewKeyMap()
+			So(m.Bind("F3", "search"), ShouldBeNil)
+			conflicts := file.Conflicts(m)
+			So(conflicts, ShouldHaveLength, 4)
+			So(conflicts[0].Line, ShouldEqual, 6)
+			So(conflicts[0].Message, ShouldContainSubstring, "bound again")
+			lines := []int{conflicts[1].Line, conflicts[2].Line, conflicts[3].Line}
+			So(lines, ShouldResemble, []int{2, 3, 4})
+			So(conflicts[3].String(), ShouldStartWith, "keys.json:4:3: F3 Rune[x] bound to \"find\" can never be typed")
+		})
+	})
+}
@@ -0,0 +1,202 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseKeyBinding(t *testing.T) {
+	Convey("Parsing key bindings", t, func() {
+		kb, err := ParseKeyBinding("Ctrl+Shift+F5")
+		So(err, ShouldBeNil)
+		So(kb, ShouldResemble, KeyBinding{{Key: KeyF5, Mod: ModCtrl | ModShift}})
+		So(kb.String(), ShouldEqual, "Shift+Ctrl+F5")
+
+		kb, err = ParseKeyBinding("  ctrl-x   Ctrl+S ")
+		So(err, ShouldBeNil)
+		So(kb, ShouldResemble, KeyBinding{
+			{Key: KeyCtrlX, Mod: ModCtrl},
+			{Key: KeyCtrlS, Mod: ModCtrl},
+		})
+		So(kb.String(), ShouldEqual, "Ctrl+X Ctrl+S")
+
+		for _, text := range []string{"Ctrl+I", "Control-i"} {
+			kb, err = ParseKeyBinding(text)
+			So(err, ShouldBeNil)
+			So(kb, ShouldResemble, KeyBinding{{Key: KeyRune, Rune: 'i', Mod: ModCtrl}})
+		}
+		kb, err = ParseKeyBinding("ctrl+tab")
+		So(err, ShouldBeNil)
+		So(kb, ShouldResemble, KeyBinding{{Key: KeyTab, Mod: ModCtrl}})
+		kb, err = ParseKeyBinding("Tab")
+		So(err, ShouldBeNil)
+		So(kb, ShouldResemble, KeyBinding{{Key: KeyTab}})
+
+		kb, err = ParseKeyBinding("Alt+x Super++ Space Shift+A é")
+		So(err, ShouldBeNil)
+		So(kb, ShouldResemble, KeyBinding{
+			{Key: KeyRune, Rune: 'x', Mod: ModAlt},
+			{Key: KeyRune, Rune: '+', Mod: ModSuper},
+			{Key: KeyRune, Rune: ' '},
+			{Key: KeyRune, Rune: 'A'},
+			{Key: KeyRune, Rune: 'é'},
+		})
+
+		Convey("round trips the names of key events", func() {
+			for _, ev := range []*EventKey{
+				NewEventKey(KeyRune, 'q', ModAlt),
+				NewEventKey(KeyRune, 'a'&0x1f, ModNone),
+				NewEventKey(KeyPgDn, 0, ModShift|ModMeta),
+				NewEventKey(KeyCtrlSpace, 0, ModCtrl),
+				NewEventKey(KeyBackspace2, 0x7f, ModNone),
+				NewEventKey(Key(1000), 7, ModNone),
+			} {
+				kb, err = ParseKeyBinding(ev.Name())
+				So(err, ShouldBeNil)
+				So(kb, ShouldResemble, KeyBinding{KeyStrokeOf(ev)})
+			}
+		})
+
+		Convey("rejects what is not a key binding", func() {
+			for _, text := range []string{"", "   ", "Ctrl+", "Ctrl+Foo", "Rune[ab]"} {
+				_, err = ParseKeyBinding(text)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
+
+func TestKeyMap(t *testing.T) {
+	Convey("Binding keys to actions", t, func() {
+		m := NewKeyMap()
+		So(m.Bind("Ctrl+X Ctrl+S", "save"), ShouldBeNil)
+		So(m.Bind("Ctrl+X Ctrl+C", "quit"), ShouldBeNil)
+		So(m.Bind("F1", "help"), ShouldBeNil)
+		So(m.Bind("Ctrl+Nope", "nothing"), ShouldNotBeNil)
+		So(m.Len(), ShouldEqual, 3)
+
+		ctrlX, _ := ParseKeyBinding("Ctrl+X")
+		action, found, prefix := m.Lookup(ctrlX)
+		So(found, ShouldBeFalse)
+		So(prefix, ShouldBeTrue)
+		save, _ := ParseKeyBinding("Ctrl+X Ctrl+S")
+		action, found, prefix = m.Lookup(save)
+		So(action, ShouldEqual, "save")
+		So(found, ShouldBeTrue)
+		So(prefix, ShouldBeFalse)
+		So(m.Bindings()[0].String(), ShouldEqual, "Ctrl+X Ctrl+C")
+
+		m.Unbind(save)
+		_, _, prefix = m.Lookup(ctrlX)
+		So(prefix, ShouldBeTrue)
+		quit, _ := ParseKeyBinding("Ctrl+X Ctrl+C")
+		m.Unbind(quit)
+		_, _, prefix = m.Lookup(ctrlX)
+		So(prefix, ShouldBeFalse)
+		m.Clear()
+		So(m.Len(), ShouldEqual, 0)
+
+		Convey("keeping Ctrl+I apart from Ctrl+Tab", func() {
+			So(m.Bind("Ctrl+I", "italic"), ShouldBeNil)
+			So(m.Bind("Ctrl+Tab", "next"), ShouldBeNil)
+			So(m.Len(), ShouldEqual, 2)
+			// as reported with the kitty keyboard protocol
+			action, found, _ = m.Lookup(KeyBinding{KeyStrokeOf(NewEventKey(KeyRune, 'i', ModCtrl))})
+			So(found, ShouldBeTrue)
+			So(action, ShouldEqual, "italic")
+			action, found, _ = m.Lookup(KeyBinding{KeyStrokeOf(NewEventKey(KeyTab, rune(KeyTab), ModCtrl))})
+			So(found, ShouldBeTrue)
+			So(action, ShouldEqual, "next")
+			So(m.Bindings()[0].String(), ShouldEqual, "Ctrl+Rune[i]")
+			italic, err := ParseKeyBinding(m.Bindings()[0].String())
+			So(err, ShouldBeNil)
+			So(italic, ShouldResemble, KeyBinding{{Key: KeyRune, Rune: 'i', Mod: ModCtrl}})
+		})
+	})
+}
+
+func TestDisplayManagerKeyMap(t *testing.T) {
+	Convey("Dispatching key bindings", t, func() {
+		d := NewDisplayManager("keymap", OffscreenDisplayTtyPath)
+		defer d.Destroy()
+		w := NewWindow("keymap", d)
+		d.SetActiveWindow(w)
+		So(d.GetChordTimeout(), ShouldEqual, DefaultChordTimeout)
+
+		var actions, keys []string
+		handler := func(who string) SignalListenerFn {
+			return func(_ []interface{}, argv ...interface{}) EventFlag {
+				actions = append(actions, who+":"+argv[1].(string))
+				return EVENT_STOP
+			}
+		}
+		w.Connect(SignalAccelAction, "keymap-test", handler("window"))
+		d.Connect(SignalAccelAction, "keymap-test", handler("manager"))
+		w.Connect(SignalEvent, "keymap-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+			if e, ok := argv[1].(*EventKey); ok {
+				keys = append(keys, e.Name())
+			}
+			return EVENT_PASS
+		})
+		So(d.GetKeyMap().Bind("Ctrl+X Ctrl+S", "save"), ShouldBeNil)
+		So(d.GetKeyMap().Bind("F1", "help"), ShouldBeNil)
+		So(w.(KeyMapWindow).GetKeyMap().Bind("F1", "window-help"), ShouldBeNil)
+		So(w.(KeyMapWindow).GetKeyMap().Bind("Ctrl+X k", "kill"), ShouldBeNil)
+		typeKeys := func(evs ...*EventKey) {
+			for _, ev := range evs {
+				d.ProcessEvent(ev)
+			}
+		}
+		ctrl := func(r rune) *EventKey {
+			return NewEventKey(KeyRune, r&0x1f, ModNone)
+		}
+
+		Convey("the window takes precedence", func() {
+			typeKeys(NewEventKey(KeyF1, 0, ModNone))
+			So(actions, ShouldResemble, []string{"window:window-help"})
+			So(keys, ShouldBeEmpty)
+		})
+
+		Convey("chords of either are typed", func() {
+			typeKeys(ctrl('x'), ctrl('s'), ctrl('x'), NewEventKey(KeyRune, 'k', ModNone))
+			So(actions, ShouldResemble, []string{"manager:save", "window:kill"})
+			So(keys, ShouldBeEmpty)
+		})
+
+		Convey("keys breaking a chord are looked up alone", func() {
+			typeKeys(ctrl('x'), NewEventKey(KeyF1, 0, ModNone), ctrl('x'), NewEventKey(KeyRune, 'q', ModNone))
+			So(actions, ShouldResemble, []string{"window:window-help"})
+			So(keys, ShouldResemble, []string{"Rune[q]"})
+		})
+
+		Convey("chords time out", func() {
+			d.SetChordTimeout(time.Millisecond * 20)
+			typeKeys(ctrl('x'))
+			time.Sleep(time.Millisecond * 40)
+			typeKeys(ctrl('s'))
+			So(actions, ShouldBeEmpty)
+			So(keys, ShouldResemble, []string{"Ctrl+S"})
+		})
+
+		Convey("key releases are ignored", func() {
+			typeKeys(NewEventKeyType(KeyF1, 0, ModNone, KeyEventRelease))
+			So(actions, ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,193 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"fmt"
+)
+
+const (
+	// SignalModalBlocked is emitted on the topmost modal window when a mouse
+	// button is pressed outside of it, with the modal window, the display
+	// manager and the mouse event swallowed as argv
+	SignalModalBlocked Signal = "modal-blocked"
+	// SignalModalClosed is emitted on a modal window once closed, with the
+	// modal window, the display manager and the result of the modal as argv
+	SignalModalClosed Signal = "modal-closed"
+)
+
+type modalEntry struct {
+	window   Window
+	previous Window
+	result   chan interface{}
+}
+
+// PushModal makes the window given, added if need be, the topmost modal
+// window.  While there are modal windows, key and mouse events are only
+// sent to the topmost of them, and to popup windows, and pressing a mouse
+// button elsewhere emits SignalModalBlocked instead.  Modal windows are
+// stacked above all toplevel windows, in the order they were pushed.  Windows
+// that are modal already are not pushed again.
+func (d *CDisplayManager) PushModal(w Window) {
+	if _, err := d.pushModal(w); err != nil {
+		d.LogErr(err)
+	}
+}
+
+// pushModal pushes the window given as a modal window, returning the channel
+// its result is sent on once closed, or an error if it is modal already
+func (d *CDisplayManager) pushModal(w Window) (chan interface{}, error) {
+	// the display manager is never locked with the modalLock held
+	previous := d.ActiveWindow()
+	d.modalLock.Lock()
+	for _, m := range d.modals {
+		if m.window.ObjectID() == w.ObjectID() {
+			d.modalLock.Unlock()
+			return nil, fmt.Errorf("window is modal already: %v", w)
+		}
+	}
+	entry := &modalEntry{
+		window:   w,
+		previous: previous,
+		result:   make(chan interface{}, 1),
+	}
+	d.modals = append(d.modals, entry)
+	d.modalLock.Unlock()
+	if owner := d.GetPointerGrab(); owner != nil && owner.ObjectID() != w.ObjectID() {
+		d.breakPointerGrab()
+	}
+	d.SetActiveWindow(w)
+	d.requestWindowDraw()
+	return entry.result, nil
+}
+
+// CloseModal closes the modal window given with the result given, removing
+// the window from the display manager.  Closing the topmost modal window
+// restores the window that was active when it was pushed.  Modal windows
+// removed with RemoveWindow are closed with a nil result.
+func (d *CDisplayManager) CloseModal(w Window, result interface{}) {
+	entry, topmost := d.dropModal(w)
+	if entry == nil {
+		d.LogError("window is not modal: %v", w)
+		return
+	}
+	d.RemoveWindow(w)
+	if topmost && entry.previous != nil {
+		d.Lock()
+		present := d.windowIndex(entry.previous) > -1
+		d.Unlock()
+		if present {
+			d.SetActiveWindow(entry.previous)
+		}
+	}
+	d.closedModal(entry, result)
+}
+
+// dropModal removes the window given from the modal stack, returning its
+// entry, or nil if not modal, and whether it was the topmost modal window
+func (d *CDisplayManager) dropModal(w Window) (entry *modalEntry, topmost bool) {
+	d.modalLock.Lock()
+	defer d.modalLock.Unlock()
+	idx := -1
+	for i, m := range d.modals {
+		if m.window.ObjectID() == w.ObjectID() {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return nil, false
+	}
+	entry = d.modals[idx]
+	d.modals = append(d.modals[:idx], d.modals[idx+1:]...)
+	for _, m := range d.modals[idx:] {
+		// modals pushed over this one restore what this one would have
+		if m.previous != nil && m.previous.ObjectID() == w.ObjectID() {
+			m.previous = entry.previous
+		}
+	}
+	return entry, idx == len(d.modals)
+}
+
+func (d *CDisplayManager) closedModal(entry *modalEntry, result interface{}) {
+	entry.result <- result
+	entry.window.Emit(SignalModalClosed, entry.window, d, result)
+}
+
+// GetModal returns the topmost modal window, or nil if there are none.
+func (d *CDisplayManager) GetModal() Window {
+	d.modalLock.Lock()
+	defer d.modalLock.Unlock()
+	if n := len(d.modals); n > 0 {
+		return d.modals[n-1].window
+	}
+	return nil
+}
+
+// AwaitModal pushes the window given as a modal window, see PushModal, and
+// waits for it to be closed with CloseModal, returning the result it was
+// closed with.  Windows that are modal already, awaited or not, are not
+// pushed again and an error is returned instead.  Like AwaitCall, AwaitModal
+// must be called from a goroutine of its own, not from callbacks, event
+// handlers or signal listeners, which would keep the modal from ever being
+// closed.
+func (d *CDisplayManager) AwaitModal(w Window) (result interface{}, err error) {
+	var results chan interface{}
+	if err = d.AwaitCall(func(_ DisplayManager) (err error) {
+		results, err = d.pushModal(w)
+		return
+	}); err != nil {
+		return nil, err
+	}
+	select {
+	case result = <-results:
+		return result, nil
+	case <-d.stopping:
+		return nil, fmt.Errorf("application stopped before the modal was closed")
+	}
+}
+
+// modalWindows returns the modal windows of the display manager, from the
+// first pushed to the topmost
+func (d *CDisplayManager) modalWindows() (windows []Window) {
+	d.modalLock.Lock()
+	defer d.modalLock.Unlock()
+	for _, m := range d.modals {
+		windows = append(windows, m.window)
+	}
+	return
+}
+
+// inputWindow returns the window key events are sent to, the topmost modal
+// window if any, otherwise the active window
+func (d *CDisplayManager) inputWindow() Window {
+	if modal := d.GetModal(); modal != nil {
+		return modal
+	}
+	return d.ActiveWindow()
+}
+
+// modalBlocks returns true if the window given, possibly nil, is beneath
+// the topmost modal window and may not receive mouse events
+func (d *CDisplayManager) modalBlocks(w Window) bool {
+	modal := d.GetModal()
+	if modal == nil {
+		return false
+	}
+	if w == nil {
+		return true
+	}
+	return w.ObjectID() != modal.ObjectID() && windowTypeOf(w) != WINDOW_POPUP
+}
@@ -0,0 +1,172 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDisplayManagerModal(t *testing.T) {
+	Convey("Modal windows", t, func() {
+		d := NewDisplayManager("modal", OffscreenDisplayTtyPath)
+		defer d.Destroy()
+		d.CaptureDisplay(OffscreenDisplayTtyPath)
+		o := d.Display().(OffscreenDisplay)
+		o.SetSize(6, 3)
+
+		var seen []string
+		newWindow := func(name string) Window {
+			w := NewWindow(name, d)
+			w.Connect(SignalEvent, "modal-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+				switch e := argv[1].(type) {
+				case *EventKey:
+					seen = append(seen, name+":"+string(e.Rune()))
+				case *EventMouse:
+					x, y := e.Position()
+					seen = append(seen, name+":"+e.State().String()+fmt.Sprintf(":%d,%d", x, y))
+				}
+				return EVENT_PASS
+			})
+			return w
+		}
+		names := func(windows []Window) (list []string) {
+			for _, w := range windows {
+				list = append(list, w.GetTitle())
+			}
+			return
+		}
+
+		a := newWindow("a")
+		b := newWindow("b")
+		dlg := newWindow("dlg")
+		d.SetActiveWindow(a)
+		d.AddWindow(b)
+		d.AddWindow(dlg)
+		d.SetWindowRegion(dlg, MakeRegion(1, 1, 2, 2))
+		d.PushModal(dlg)
+		So(d.GetModal(), ShouldEqual, dlg)
+		So(d.ActiveWindow(), ShouldEqual, dlg)
+
+		Convey("receive the key events", func() {
+			d.ProcessEvent(NewEventKey(KeyRune, 'x', ModNone))
+			d.SetActiveWindow(b)
+			d.ProcessEvent(NewEventKey(KeyRune, 'y', ModNone))
+			So(seen, ShouldResemble, []string{"dlg:x", "dlg:y"})
+			So(names(d.GetWindowStack()), ShouldResemble, []string{"a", "b", "dlg"})
+		})
+
+		Convey("swallow mouse events for windows beneath them", func() {
+			var blocked []*EventMouse
+			dlg.Connect(SignalModalBlocked, "modal-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+				blocked = append(blocked, argv[2].(*EventMouse))
+				return EVENT_PASS
+			})
+			var prev *EventMouse
+			mouse := func(x, y int, btn ButtonMask) {
+				prev = newEventMouse(prev, x, y, btn, ModNone)
+				d.ProcessEvent(prev)
+			}
+			mouse(0, 0, Button1)
+			mouse(0, 0, ButtonNone)
+			So(seen, ShouldBeEmpty)
+			So(blocked, ShouldHaveLength, 1)
+			So(d.ActiveWindow(), ShouldEqual, dlg)
+			mouse(2, 2, Button1)
+			So(seen, ShouldResemble, []string{"dlg:BUTTON_PRESS:1,1"})
+		})
+
+		Convey("restore the active window when closed", func() {
+			var closed []interface{}
+			dlg.Connect(SignalModalClosed, "modal-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+				closed = append(closed, argv[2])
+				return EVENT_PASS
+			})
+			d.CloseModal(dlg, "ok")
+			So(closed, ShouldResemble, []interface{}{"ok"})
+			So(d.GetModal(), ShouldBeNil)
+			So(d.ActiveWindow(), ShouldEqual, a)
+			So(names(d.GetWindows()), ShouldResemble, []string{"a", "b"})
+			d.ProcessEvent(NewEventKey(KeyRune, 'z', ModNone))
+			So(seen, ShouldResemble, []string{"a:z"})
+		})
+
+		Convey("restore the window active beneath a modal closed above", func() {
+			top := newWindow("top")
+			d.PushModal(top)
+			d.CloseModal(dlg, nil)
+			d.CloseModal(top, nil)
+			So(d.ActiveWindow(), ShouldEqual, a)
+		})
+
+		Convey("are closed when removed", func() {
+			d.RemoveWindow(dlg)
+			So(d.GetModal(), ShouldBeNil)
+		})
+
+		Convey("cannot be awaited unless running", func() {
+			_, err := d.AwaitModal(newWindow("await"))
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Awaiting modal windows", t, func() {
+		d := NewDisplayManager("modal", OffscreenDisplayTtyPath)
+		defer d.Destroy()
+		d.CaptureDisplay(OffscreenDisplayTtyPath)
+		a := NewWindow("a", d)
+		d.SetActiveWindow(a)
+
+		stopped := make(chan struct{})
+		go func() {
+			_ = d.Run()
+			close(stopped)
+		}()
+		defer func() {
+			d.RequestQuit()
+			<-stopped
+		}()
+		deadline := time.Now().Add(time.Second)
+		for !d.IsRunning() && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond * 5)
+		}
+
+		dlg := NewWindow("dlg", d)
+		var wg sync.WaitGroup
+		var result interface{}
+		var err error
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err = d.AwaitModal(dlg)
+		}()
+		deadline = time.Now().Add(time.Second)
+		for d.GetModal() == nil && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond * 5)
+		}
+		So(d.GetModal(), ShouldEqual, dlg)
+		_, again := d.AwaitModal(dlg)
+		So(again, ShouldNotBeNil)
+		d.CloseModal(dlg, 42)
+		wg.Wait()
+		So(err, ShouldBeNil)
+		So(result, ShouldEqual, 42)
+		So(d.ActiveWindow(), ShouldEqual, a)
+	})
+}
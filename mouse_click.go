@@ -0,0 +1,125 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"time"
+)
+
+const (
+	// DefaultClickTimeout is the longest time between the presses of a
+	// button counted as clicks of the same series, such as a double click
+	DefaultClickTimeout = time.Millisecond * 400
+	// DefaultClickTolerance is the number of cells the mouse may move, in
+	// either direction, between the clicks of the same series
+	DefaultClickTolerance = 1
+)
+
+// SetClickTimeout sets the longest time between the presses of a button
+// counted as clicks of the same series.  A timeout of zero or less restores
+// DefaultClickTimeout.
+func (d *CDisplayManager) SetClickTimeout(timeout time.Duration) {
+	d.clickLock.Lock()
+	defer d.clickLock.Unlock()
+	if timeout <= 0 {
+		timeout = DefaultClickTimeout
+	}
+	d.clickTimeout = timeout
+}
+
+func (d *CDisplayManager) GetClickTimeout() time.Duration {
+	d.clickLock.Lock()
+	defer d.clickLock.Unlock()
+	return d.clickTimeout
+}
+
+// SetClickTolerance sets the number of cells the mouse may move, in either
+// direction, between the clicks of the same series.  A click is also only
+// made if the button is released within this distance of where it was
+// pressed.  Negative tolerances are the same as zero.
+func (d *CDisplayManager) SetClickTolerance(cells int) {
+	d.clickLock.Lock()
+	defer d.clickLock.Unlock()
+	if cells < 0 {
+		cells = 0
+	}
+	d.clickTolerance = cells
+}
+
+func (d *CDisplayManager) GetClickTolerance() int {
+	d.clickLock.Lock()
+	defer d.clickLock.Unlock()
+	return d.clickTolerance
+}
+
+// synthesizeClicks returns the event given, followed by the click event
+// synthesized when it is the release of a button clicked.  The click count
+// of button presses and releases is set along the way.
+func (d *CDisplayManager) synthesizeClicks(evt Event) []Event {
+	e, ok := evt.(*EventMouse)
+	if !ok {
+		return []Event{evt}
+	}
+	d.clickLock.Lock()
+	defer d.clickLock.Unlock()
+	near := func(x, y int) bool {
+		dx, dy := x-d.clickX, y-d.clickY
+		return dx >= -d.clickTolerance && dx <= d.clickTolerance &&
+			dy >= -d.clickTolerance && dy <= d.clickTolerance
+	}
+	switch e.State() {
+	case BUTTON_PRESS:
+		if d.clickCount > 0 && e.b == d.clickButton &&
+			e.t.Sub(d.clickAt) <= d.clickTimeout && near(e.x, e.y) {
+			d.clickCount++
+		} else {
+			d.clickCount = 1
+			d.clickX, d.clickY = e.x, e.y
+		}
+		d.clickAt = e.t
+		d.clickButton = e.b
+		e.n = d.clickCount
+	case BUTTON_RELEASE:
+		if d.clickCount == 0 || e.b != d.clickButton {
+			break
+		}
+		e.n = d.clickCount
+		if !near(e.x, e.y) {
+			d.clickCount = 0
+			break
+		}
+		state := MOUSE_CLICK
+		switch {
+		case d.clickCount == 2:
+			state = MOUSE_DOUBLE_CLICK
+		case d.clickCount > 2:
+			state = MOUSE_TRIPLE_CLICK
+		}
+		click := &EventMouse{
+			t:   e.t,
+			btn: e.btn,
+			mod: e.mod,
+			x:   e.x,
+			y:   e.y,
+			s:   state,
+			b:   e.b,
+			n:   d.clickCount,
+		}
+		return []Event{e, click}
+	case DRAG_START, DRAG_MOVE, DRAG_STOP, WHEEL_PULSE:
+		d.clickCount = 0
+	}
+	return []Event{e}
+}
@@ -0,0 +1,100 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDisplayManagerClicks(t *testing.T) {
+	Convey("Counting mouse clicks", t, func() {
+		d := NewDisplayManager("clicks", OffscreenDisplayTtyPath)
+		defer d.Destroy()
+		So(d.GetClickTimeout(), ShouldEqual, DefaultClickTimeout)
+		So(d.GetClickTolerance(), ShouldEqual, DefaultClickTolerance)
+
+		var seen []*EventMouse
+		d.Connect(SignalEventMouse, "clicks-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+			seen = append(seen, argv[1].(*EventMouse))
+			return EVENT_PASS
+		})
+		var prev *EventMouse
+		at := time.Now()
+		mouse := func(delay time.Duration, x, y int, btn ButtonMask) {
+			at = at.Add(delay)
+			prev = newEventMouse(prev, x, y, btn, ModNone)
+			prev.t = at
+			d.ProcessEvent(prev)
+		}
+		click := func(delay time.Duration, x, y int) {
+			mouse(delay, x, y, Button1)
+			mouse(time.Millisecond*50, x, y, ButtonNone)
+		}
+		states := func() (states []MouseState, counts []int) {
+			for _, e := range seen {
+				states = append(states, e.State())
+				counts = append(counts, e.ClickCount())
+			}
+			return
+		}
+
+		Convey("clicks in quick succession are counted", func() {
+			click(0, 5, 5)
+			click(time.Millisecond*100, 5, 6)
+			click(time.Millisecond*100, 6, 6)
+			s, n := states()
+			So(s, ShouldResemble, []MouseState{
+				BUTTON_PRESS, BUTTON_RELEASE, MOUSE_CLICK,
+				BUTTON_PRESS, BUTTON_RELEASE, MOUSE_DOUBLE_CLICK,
+				BUTTON_PRESS, BUTTON_RELEASE, MOUSE_TRIPLE_CLICK,
+			})
+			So(n, ShouldResemble, []int{1, 1, 1, 2, 2, 2, 3, 3, 3})
+			So(seen[5].IsClicked(), ShouldBeTrue)
+			So(seen[4].IsClicked(), ShouldBeFalse)
+			So(seen[5].Button(), ShouldEqual, Button1)
+		})
+
+		Convey("slow or distant clicks start over", func() {
+			click(0, 5, 5)
+			click(DefaultClickTimeout+time.Millisecond*100, 5, 5)
+			click(time.Millisecond*100, 9, 5)
+			_, n := states()
+			So(n, ShouldResemble, []int{1, 1, 1, 1, 1, 1, 1, 1, 1})
+		})
+
+		Convey("the threshold and tolerance are configurable", func() {
+			d.SetClickTimeout(time.Second * 2)
+			d.SetClickTolerance(5)
+			click(0, 5, 5)
+			click(time.Second, 9, 5)
+			_, n := states()
+			So(n[5], ShouldEqual, 2)
+			d.SetClickTimeout(0)
+			So(d.GetClickTimeout(), ShouldEqual, DefaultClickTimeout)
+		})
+
+		Convey("dragging is not clicking", func() {
+			mouse(0, 5, 5, Button1)
+			mouse(time.Millisecond*10, 8, 5, Button1)
+			mouse(time.Millisecond*10, 8, 5, ButtonNone)
+			s, n := states()
+			So(s, ShouldResemble, []MouseState{BUTTON_PRESS, DRAG_START, DRAG_STOP})
+			So(n, ShouldResemble, []int{1, 0, 0})
+		})
+	})
+}
@@ -29,6 +29,9 @@ const (
 	DRAG_START
 	DRAG_MOVE
 	DRAG_STOP
+	MOUSE_CLICK
+	MOUSE_DOUBLE_CLICK
+	MOUSE_TRIPLE_CLICK
 )
 
 type IMouseState interface {
@@ -56,14 +59,17 @@ func (i MouseState) Toggle(m MouseState) MouseState {
 }
 
 const (
-	_MouseState_name_0 = "MOUSE_NONE"
-	_MouseState_name_1 = "MOUSE_MOVE"
-	_MouseState_name_2 = "BUTTON_PRESS"
-	_MouseState_name_3 = "BUTTON_RELEASE"
-	_MouseState_name_4 = "WHEEL_PULSE"
-	_MouseState_name_5 = "DRAG_START"
-	_MouseState_name_6 = "DRAG_MOVE"
-	_MouseState_name_7 = "DRAG_STOP"
+	_MouseState_name_0  = "MOUSE_NONE"
+	_MouseState_name_1  = "MOUSE_MOVE"
+	_MouseState_name_2  = "BUTTON_PRESS"
+	_MouseState_name_3  = "BUTTON_RELEASE"
+	_MouseState_name_4  = "WHEEL_PULSE"
+	_MouseState_name_5  = "DRAG_START"
+	_MouseState_name_6  = "DRAG_MOVE"
+	_MouseState_name_7  = "DRAG_STOP"
+	_MouseState_name_8  = "MOUSE_CLICK"
+	_MouseState_name_9  = "MOUSE_DOUBLE_CLICK"
+	_MouseState_name_10 = "MOUSE_TRIPLE_CLICK"
 )
 
 func (i MouseState) String() string {
@@ -84,6 +90,12 @@ func (i MouseState) String() string {
 		return _MouseState_name_6
 	case i == 128:
 		return _MouseState_name_7
+	case i == 256:
+		return _MouseState_name_8
+	case i == 512:
+		return _MouseState_name_9
+	case i == 1024:
+		return _MouseState_name_10
 	default:
 		return "MouseState(" + strconv.FormatInt(int64(i), 10) + ")"
 	}
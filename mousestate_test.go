@@ -38,6 +38,9 @@ func TestMouseState(t *testing.T) {
 		So(DRAG_START.String(), ShouldEqual, "DRAG_START")
 		So(DRAG_MOVE.String(), ShouldEqual, "DRAG_MOVE")
 		So(DRAG_STOP.String(), ShouldEqual, "DRAG_STOP")
+		So(MOUSE_CLICK.String(), ShouldEqual, "MOUSE_CLICK")
+		So(MOUSE_DOUBLE_CLICK.String(), ShouldEqual, "MOUSE_DOUBLE_CLICK")
+		So(MOUSE_TRIPLE_CLICK.String(), ShouldEqual, "MOUSE_TRIPLE_CLICK")
 		So((DRAG_STOP + 1).String(), ShouldEqual, "MouseState(129)")
 	})
 }
@@ -82,6 +82,27 @@ type OffscreenDisplay interface {
 	// GetCursor returns the cursor details.
 	GetCursor() (x int, y int, visible bool)
 
+	// GetCursorStyle returns the cursor style and color last set.  Both
+	// are restored to their defaults when the display is closed.
+	GetCursorStyle() (style CursorStyle, color Color)
+
+	// GetClipboard returns the contents of the given clipboard selection,
+	// offscreen displays have an in-memory clipboard.
+	GetClipboard(selection ClipboardSelection) string
+
+	// GetHyperlink returns the hyperlink target and id of the cell at the
+	// given location, as last shown.  The url is empty for cells that are
+	// not part of a hyperlink.
+	GetHyperlink(x, y int) (url string, id string)
+
+	// GetTitle returns the title last set, which is kept when the display
+	// is closed.
+	GetTitle() string
+
+	// IsSuspended returns true if the display is suspended, and nothing
+	// shown, until resumed.
+	IsSuspended() bool
+
 	Display
 }
 
@@ -113,8 +134,17 @@ type COffscreenDisplay struct {
 	cursorX   int
 	cursorY   int
 	cursorVis bool
+	cursorSty CursorStyle
+	cursorCol Color
+	syncMode  SyncOutputMode
+	clipboard map[ClipboardSelection]string
+	title     string
 	mouse     bool
+	lastMouse *EventMouse
 	paste     bool
+	focus     bool
+	suspended bool
+	kitty     KittyKeyboardFlags
 	charset   string
 	encoder   transform.Transformer
 	decoder   transform.Transformer
@@ -159,7 +189,12 @@ func (o *COffscreenDisplay) Init() error {
 func (o *COffscreenDisplay) Close() {
 	o.Lock()
 	defer o.Unlock()
+	if o.finished {
+		return
+	}
 	o.finished = true
+	o.cursorSty = CursorStyleDefault
+	o.cursorCol = ColorDefault
 	o.back.Resize(0, 0)
 	if o.quit != nil {
 		close(o.quit)
@@ -267,6 +302,7 @@ func (o *COffscreenDisplay) drawCell(x, y int) int {
 }
 
 func (o *COffscreenDisplay) ShowCursor(x, y int) {
+	o.Lock()
 	o.cursorX, o.cursorY = x, y
 	o.showCursor()
 	o.Unlock()
@@ -276,6 +312,72 @@ func (o *COffscreenDisplay) HideCursor() {
 	o.ShowCursor(-1, -1)
 }
 
+func (o *COffscreenDisplay) SetCursorStyle(style CursorStyle) {
+	if !style.IsValid() {
+		style = CursorStyleDefault
+	}
+	o.Lock()
+	o.cursorSty = style
+	o.Unlock()
+}
+
+func (o *COffscreenDisplay) SetCursorColor(color Color) {
+	if color.Hex() < 0 {
+		color = ColorDefault
+	}
+	o.Lock()
+	o.cursorCol = color
+	o.Unlock()
+}
+
+// SetSyncOutput records the mode given, there is nothing to synchronize so
+// the output is only reported as synchronized if SyncOutputEnabled.
+func (o *COffscreenDisplay) SetSyncOutput(mode SyncOutputMode) {
+	o.Lock()
+	o.syncMode = mode
+	o.Unlock()
+}
+
+func (o *COffscreenDisplay) HasSyncOutput() bool {
+	o.Lock()
+	defer o.Unlock()
+	return o.syncMode == SyncOutputEnabled
+}
+
+// SetClipboard sets the given selection of the in-memory clipboard
+func (o *COffscreenDisplay) SetClipboard(selection ClipboardSelection, text string) error {
+	if err := checkClipboard(selection, text); err != nil {
+		return err
+	}
+	o.Lock()
+	defer o.Unlock()
+	if o.clipboard == nil {
+		o.clipboard = make(map[ClipboardSelection]string)
+	}
+	o.clipboard[selection] = text
+	return nil
+}
+
+// RequestClipboard posts an EventClipboard with the contents of the given
+// selection of the in-memory clipboard
+func (o *COffscreenDisplay) RequestClipboard(selection ClipboardSelection) error {
+	if !selection.IsValid() {
+		return fmt.Errorf("invalid clipboard selection: %q", string(selection))
+	}
+	o.Lock()
+	text := o.clipboard[selection]
+	o.Unlock()
+	return o.PostEvent(NewEventClipboard(selection, text))
+}
+
+// GetClipboard returns the contents of the given selection of the in-memory
+// clipboard
+func (o *COffscreenDisplay) GetClipboard(selection ClipboardSelection) string {
+	o.Lock()
+	defer o.Unlock()
+	return o.clipboard[selection]
+}
+
 func (o *COffscreenDisplay) showCursor() {
 
 	x, y := o.cursorX, o.cursorY
@@ -294,6 +396,9 @@ func (o *COffscreenDisplay) hideCursor() {
 func (o *COffscreenDisplay) Show() {
 	o.Lock()
 	defer o.Unlock()
+	if o.suspended {
+		return
+	}
 	o.resize()
 	o.draw()
 }
@@ -340,6 +445,34 @@ func (o *COffscreenDisplay) DisablePaste() {
 	o.paste = false
 }
 
+func (o *COffscreenDisplay) EnableFocus() {
+	o.focus = true
+}
+
+func (o *COffscreenDisplay) DisableFocus() {
+	o.focus = false
+}
+
+// EnableKittyKeyboard records the flags given, keys injected are delivered
+// as they are regardless
+func (o *COffscreenDisplay) EnableKittyKeyboard(flags KittyKeyboardFlags) {
+	o.Lock()
+	defer o.Unlock()
+	o.kitty = flags
+}
+
+func (o *COffscreenDisplay) DisableKittyKeyboard() {
+	o.Lock()
+	defer o.Unlock()
+	o.kitty = 0
+}
+
+func (o *COffscreenDisplay) HasKittyKeyboard() bool {
+	o.Lock()
+	defer o.Unlock()
+	return o.kitty != 0
+}
+
 func (o *COffscreenDisplay) Size() (w, h int) {
 	w, h = o.back.Size()
 	return
@@ -382,7 +515,10 @@ func (o *COffscreenDisplay) PostEvent(ev Event) error {
 }
 
 func (o *COffscreenDisplay) InjectMouse(x, y int, buttons ButtonMask, mod ModMask) {
-	ev := NewEventMouse(x, y, buttons, mod)
+	o.Lock()
+	o.lastMouse = newEventMouse(o.lastMouse, x, y, buttons, mod)
+	ev := o.lastMouse
+	o.Unlock()
 	_ = o.PostEvent(ev)
 }
 
@@ -440,6 +576,11 @@ outer:
 }
 
 func (o *COffscreenDisplay) Sync() {
+	o.Lock()
+	if o.suspended {
+		o.Unlock()
+		return
+	}
 	o.clear = true
 	o.resize()
 	o.back.Invalidate()
@@ -447,6 +588,36 @@ func (o *COffscreenDisplay) Sync() {
 	o.Unlock()
 }
 
+// Suspend stops showing anything until the display is resumed.
+func (o *COffscreenDisplay) Suspend() error {
+	o.Lock()
+	defer o.Unlock()
+	if o.finished {
+		return ErrNoDisplay
+	}
+	o.suspended = true
+	return nil
+}
+
+// Resume shows the whole display again, undoing Suspend.
+func (o *COffscreenDisplay) Resume() error {
+	o.Lock()
+	if o.finished {
+		o.Unlock()
+		return ErrNoDisplay
+	}
+	o.suspended = false
+	o.Unlock()
+	o.Sync()
+	return nil
+}
+
+func (o *COffscreenDisplay) IsSuspended() bool {
+	o.Lock()
+	defer o.Unlock()
+	return o.suspended
+}
+
 func (o *COffscreenDisplay) CharacterSet() string {
 	return o.charset
 }
@@ -480,6 +651,34 @@ func (o *COffscreenDisplay) GetCursor() (int, int, bool) {
 	return x, y, vis
 }
 
+func (o *COffscreenDisplay) GetCursorStyle() (CursorStyle, Color) {
+	o.Lock()
+	defer o.Unlock()
+	return o.cursorSty, o.cursorCol
+}
+
+func (o *COffscreenDisplay) GetHyperlink(x, y int) (string, string) {
+	o.Lock()
+	defer o.Unlock()
+	if x < 0 || y < 0 || x >= o.physW || y >= o.physH {
+		return "", ""
+	}
+	return o.front[(y*o.physW)+x].Style.Hyperlink()
+}
+
+// SetTitle records the title given, see GetTitle
+func (o *COffscreenDisplay) SetTitle(title string) {
+	o.Lock()
+	defer o.Unlock()
+	o.title = title
+}
+
+func (o *COffscreenDisplay) GetTitle() string {
+	o.Lock()
+	defer o.Unlock()
+	return o.title
+}
+
 func (o *COffscreenDisplay) RegisterRuneFallback(r rune, subst string) {
 	o.Lock()
 	defer o.Unlock()
@@ -42,6 +42,9 @@ type COffscreenWindow struct {
 
 	title   string
 	display OffscreenDisplay
+	keyMap  KeyMap
+
+	windowType WindowType
 }
 
 func NewOffscreenWindow(title string) Window {
@@ -62,6 +65,7 @@ func (w *COffscreenWindow) Init() bool {
 		return true
 	}
 	w.CObject.Init()
+	w.keyMap = NewKeyMap()
 	return false
 }
 
@@ -75,6 +79,24 @@ func (w *COffscreenWindow) GetTitle() string {
 	return w.title
 }
 
+// GetKeyMap returns the key bindings of the window, which apply while it is
+// the active window.
+func (w *COffscreenWindow) GetKeyMap() KeyMap {
+	return w.keyMap
+}
+
+func (w *COffscreenWindow) SetKeyMap(keyMap KeyMap) {
+	w.keyMap = keyMap
+}
+
+func (w *COffscreenWindow) GetWindowType() WindowType {
+	return w.windowType
+}
+
+func (w *COffscreenWindow) SetWindowType(windowType WindowType) {
+	w.windowType = windowType
+}
+
 func (w *COffscreenWindow) GetDisplayManager() DisplayManager {
 	// return w.display
 	return nil
@@ -0,0 +1,136 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+var (
+	// DefaultPasteLimit is the most bytes of a bracketed paste buffered by
+	// a display manager, larger pastes are delivered key by key
+	DefaultPasteLimit = 1 << 20
+)
+
+// SetPasteBuffering determines how bracketed pastes are delivered.  By
+// default pastes are not buffered and are delivered as an EventPaste marking
+// the start, an EventKey for each key and an EventPaste marking the end.
+// When buffering, the keys of a paste are collected and delivered as one
+// EventPaste carrying the pasted text, see EventPaste.HasText, except for
+// pastes of more than limit bytes which are delivered as when not buffering.
+// A limit of zero or less uses DefaultPasteLimit.
+func (d *CDisplayManager) SetPasteBuffering(buffer bool, limit int) {
+	d.pasteLock.Lock()
+	defer d.pasteLock.Unlock()
+	if limit <= 0 {
+		limit = DefaultPasteLimit
+	}
+	d.pasteBuffer = buffer
+	d.pasteLimit = limit
+}
+
+// GetPasteBuffering returns whether bracketed pastes are buffered, and the
+// most bytes of a paste that are buffered.
+func (d *CDisplayManager) GetPasteBuffering() (buffer bool, limit int) {
+	d.pasteLock.Lock()
+	defer d.pasteLock.Unlock()
+	return d.pasteBuffer, d.pasteLimit
+}
+
+// bufferPaste collects the keys of a bracketed paste, returning the events to
+// be processed in place of the one given, if any
+func (d *CDisplayManager) bufferPaste(evt Event) []Event {
+	d.pasteLock.Lock()
+	defer d.pasteLock.Unlock()
+	if !d.pasteBuffer && d.pasteStart == nil {
+		return []Event{evt}
+	}
+	switch e := evt.(type) {
+	case *EventPaste:
+		switch {
+		case e.Start() && d.pasteBuffer:
+			// a paste not ended is delivered as it is
+			events := d.flushPaste()
+			d.pasteStart = e
+			return events
+		case e.End() && d.pasteStart != nil:
+			if d.pasteOverflow {
+				d.resetPaste()
+				return []Event{evt}
+			}
+			text := d.pasteText.String()
+			d.resetPaste()
+			return []Event{NewEventPasteText(text)}
+		}
+	case *EventKey:
+		if d.pasteStart == nil || d.pasteOverflow {
+			break
+		}
+		d.pasteKeys = append(d.pasteKeys, e)
+		d.pasteText.WriteString(d.pasteKeyText(e))
+		if d.pasteText.Len() > d.pasteLimit {
+			d.LogTrace("paste exceeds %d bytes, delivering keys", d.pasteLimit)
+			events := d.flushPaste()
+			d.pasteOverflow = true
+			return events
+		}
+		return nil
+	}
+	return []Event{evt}
+}
+
+// flushPaste returns the start of the paste in progress and the keys
+// buffered, if any, for delivery as they are
+func (d *CDisplayManager) flushPaste() (events []Event) {
+	if d.pasteStart != nil && !d.pasteOverflow {
+		events = append(events, d.pasteStart)
+		events = append(events, d.pasteKeys...)
+	}
+	d.resetPaste()
+	return
+}
+
+func (d *CDisplayManager) resetPaste() {
+	d.pasteStart = nil
+	d.pasteKeys = nil
+	d.pasteText.Reset()
+	d.pasteOverflow = false
+	d.pasteCR = false
+}
+
+// pasteKeyText returns the text the given key of a paste represents, line
+// endings are normalized to a newline and keys which do not represent text,
+// such as the arrow keys, are left out
+func (d *CDisplayManager) pasteKeyText(e *EventKey) string {
+	if e.EventType() == KeyEventRelease {
+		return ""
+	}
+	cr := d.pasteCR
+	d.pasteCR = false
+	switch k := e.Key(); {
+	case k == KeyCR:
+		d.pasteCR = true
+		return "\n"
+	case k == KeyLF:
+		if cr {
+			return ""
+		}
+		return "\n"
+	case k == KeyRune:
+		if e.Modifiers().Has(ModAlt) {
+			return "\x1b" + string(e.Rune())
+		}
+		return string(e.Rune())
+	case k <= KeyUS, k == KeyDEL:
+		return string(rune(k))
+	}
+	return ""
+}
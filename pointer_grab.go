@@ -0,0 +1,154 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+// SignalGrabBroken is emitted on the owner of a pointer grab, and then on
+// the display manager, when the grab ends without the owner releasing it.
+// The argv of the owner's listeners is the owner and the display manager,
+// and that of the display manager's listeners is the display manager and
+// the owner.
+const SignalGrabBroken Signal = "grab-broken"
+
+// the handle connecting the display manager to the destruction of the owner
+// of a pointer grab
+const pointerGrabHandle = "display-pointer-grab"
+
+// GrabPointer sends all mouse events to the owner given, instead of the
+// active window, until the grab is released with ReleasePointer, or the
+// button held is let go, ending a click or drag.  Owners that process events,
+// such as windows, are given the events to process, others have
+// SignalEventMouse emitted.
+//
+// The grab is broken, see SignalGrabBroken, when the active window changes,
+// a window is added, the terminal loses focus, the owner is destroyed or
+// another object grabs the pointer.
+func (d *CDisplayManager) GrabPointer(owner Object) {
+	if owner == nil {
+		d.ReleasePointer()
+		return
+	}
+	d.grabLock.Lock()
+	prev := d.grabOwner
+	if prev == owner {
+		d.grabLock.Unlock()
+		return
+	}
+	d.grabOwner = owner
+	d.grabEnding = false
+	d.grabLock.Unlock()
+	if prev != nil {
+		_ = prev.Disconnect(SignalDestroy, pointerGrabHandle)
+		d.emitGrabBroken(prev)
+	}
+	owner.Connect(SignalDestroy, pointerGrabHandle, func(_ []interface{}, _ ...interface{}) EventFlag {
+		// not disconnected here, the owner is emitting the signal
+		d.grabLock.Lock()
+		grabbed := d.grabOwner == owner
+		if grabbed {
+			d.grabOwner = nil
+		}
+		d.grabLock.Unlock()
+		if grabbed {
+			d.emitGrabBroken(owner)
+		}
+		return EVENT_PASS
+	})
+	d.LogTrace("pointer grabbed by: %v", owner)
+}
+
+// ReleasePointer ends the pointer grab in effect, if any, such that mouse
+// events are sent to the active window again.
+func (d *CDisplayManager) ReleasePointer() {
+	if owner := d.endPointerGrab(); owner != nil {
+		d.LogTrace("pointer released by: %v", owner)
+	}
+}
+
+// GetPointerGrab returns the owner of the pointer grab in effect, or nil.
+func (d *CDisplayManager) GetPointerGrab() Object {
+	d.grabLock.Lock()
+	defer d.grabLock.Unlock()
+	return d.grabOwner
+}
+
+// IsPointerGrabbed returns true if a pointer grab is in effect.
+func (d *CDisplayManager) IsPointerGrabbed() bool {
+	return d.GetPointerGrab() != nil
+}
+
+func (d *CDisplayManager) endPointerGrab() (owner Object) {
+	d.grabLock.Lock()
+	owner = d.grabOwner
+	d.grabOwner = nil
+	d.grabEnding = false
+	d.grabLock.Unlock()
+	if owner != nil {
+		_ = owner.Disconnect(SignalDestroy, pointerGrabHandle)
+	}
+	return
+}
+
+// endReleasedPointerGrab ends the pointer grab in effect if the button held
+// was let go, see processGrabbedMouse
+func (d *CDisplayManager) endReleasedPointerGrab() {
+	d.grabLock.Lock()
+	ending := d.grabEnding
+	d.grabLock.Unlock()
+	if ending {
+		d.endPointerGrab()
+	}
+}
+
+// breakPointerGrab ends the pointer grab in effect, if any, notifying the
+// owner that it was broken
+func (d *CDisplayManager) breakPointerGrab() {
+	if owner := d.endPointerGrab(); owner != nil {
+		d.emitGrabBroken(owner)
+	}
+}
+
+func (d *CDisplayManager) emitGrabBroken(owner Object) {
+	d.LogTrace("pointer grab broken: %v", owner)
+	owner.Emit(SignalGrabBroken, owner, d)
+	d.Emit(SignalGrabBroken, d, owner)
+}
+
+// processGrabbedMouse sends the mouse event given to the owner of the
+// pointer grab, and returns false if there is no grab in effect.  When the
+// button held is let go, the grab ends once the click synthesized for it, if
+// any, is sent to the owner too.
+func (d *CDisplayManager) processGrabbedMouse(e *EventMouse) (f EventFlag, grabbed bool) {
+	d.grabLock.Lock()
+	owner := d.grabOwner
+	if owner != nil && (e.IsDragStopped() || e.IsReleased()) {
+		d.grabEnding = true
+	}
+	d.grabLock.Unlock()
+	if owner == nil {
+		return EVENT_PASS, false
+	}
+	if w, ok := owner.(Window); ok {
+		// windows are given positions within their own region
+		f = w.ProcessEvent(d.windowMouseEvent(w, e))
+	} else if p, ok := owner.(interface{ ProcessEvent(evt Event) EventFlag }); ok {
+		f = p.ProcessEvent(e)
+	} else {
+		f = owner.Emit(SignalEventMouse, owner, e)
+	}
+	if f == EVENT_STOP {
+		return EVENT_STOP, true
+	}
+	return d.Emit(SignalEventMouse, d, e), true
+}
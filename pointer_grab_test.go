@@ -0,0 +1,119 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDisplayManagerPointerGrab(t *testing.T) {
+	Convey("Grabbing the pointer", t, func() {
+		d := NewDisplayManager("grab", OffscreenDisplayTtyPath)
+		defer d.Destroy()
+		w1 := NewWindow("one", d)
+		w2 := NewWindow("two", d)
+		d.SetActiveWindow(w1)
+		So(d.IsPointerGrabbed(), ShouldBeFalse)
+
+		var seen, broken []string
+		track := func(name string, w Window) {
+			w.Connect(SignalEvent, "grab-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+				if e, ok := argv[1].(*EventMouse); ok {
+					seen = append(seen, name+":"+e.State().String())
+				}
+				return EVENT_PASS
+			})
+			w.Connect(SignalGrabBroken, "grab-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+				broken = append(broken, name)
+				return EVENT_PASS
+			})
+		}
+		track("one", w1)
+		track("two", w2)
+		var prev *EventMouse
+		mouse := func(x, y int, btn ButtonMask) {
+			prev = newEventMouse(prev, x, y, btn, ModNone)
+			d.ProcessEvent(prev)
+		}
+
+		Convey("sends mouse events to the owner until the drag stops", func() {
+			mouse(1, 1, Button1)
+			d.GrabPointer(w2)
+			So(d.IsPointerGrabbed(), ShouldBeTrue)
+			So(d.GetPointerGrab(), ShouldEqual, w2)
+			mouse(5, 1, Button1)
+			mouse(9, 1, Button1)
+			mouse(9, 1, ButtonNone)
+			So(d.IsPointerGrabbed(), ShouldBeFalse)
+			mouse(3, 3, ButtonNone)
+			So(seen, ShouldResemble, []string{
+				"one:BUTTON_PRESS", "two:DRAG_START", "two:DRAG_MOVE", "two:DRAG_STOP", "one:MOUSE_MOVE",
+			})
+			So(broken, ShouldBeEmpty)
+		})
+
+		Convey("sends the click of the button let go to the owner", func() {
+			d.GrabPointer(w2)
+			mouse(1, 1, Button1)
+			mouse(1, 1, ButtonNone)
+			So(d.IsPointerGrabbed(), ShouldBeFalse)
+			mouse(3, 3, ButtonNone)
+			So(seen, ShouldResemble, []string{
+				"two:BUTTON_PRESS", "two:BUTTON_RELEASE", "two:MOUSE_CLICK", "one:MOUSE_MOVE",
+			})
+			So(broken, ShouldBeEmpty)
+		})
+
+		Convey("can be released", func() {
+			d.GrabPointer(w2)
+			d.ReleasePointer()
+			mouse(3, 3, ButtonNone)
+			So(seen, ShouldResemble, []string{"one:MOUSE_MOVE"})
+			So(broken, ShouldBeEmpty)
+		})
+
+		Convey("is broken when windows or focus change", func() {
+			d.GrabPointer(w2)
+			d.SetActiveWindow(w2)
+			So(broken, ShouldResemble, []string{"two"})
+			d.GrabPointer(w1)
+			d.ProcessEvent(NewEventFocus(false))
+			So(broken, ShouldResemble, []string{"two", "one"})
+			d.GrabPointer(w1)
+			d.AddWindow(NewWindow("three", d))
+			So(broken, ShouldResemble, []string{"two", "one", "one"})
+			d.GrabPointer(w1)
+			d.GrabPointer(w2)
+			So(broken, ShouldResemble, []string{"two", "one", "one", "one"})
+			So(d.GetPointerGrab(), ShouldEqual, w2)
+		})
+
+		Convey("is broken when the owner is destroyed", func() {
+			o := &CObject{}
+			o.Init()
+			var managerBroken []interface{}
+			d.Connect(SignalGrabBroken, "grab-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+				managerBroken = append(managerBroken, argv[1])
+				return EVENT_PASS
+			})
+			d.GrabPointer(o)
+			o.Destroy()
+			So(d.IsPointerGrabbed(), ShouldBeFalse)
+			So(managerBroken, ShouldHaveLength, 1)
+		})
+	})
+}
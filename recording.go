@@ -0,0 +1,529 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Session recordings are asciicast v2 files, see:
+//
+//	https://github.com/asciinema/asciinema/blob/develop/doc/asciicast-v2.md
+//
+// Rendered frames are recorded as "o" (output) events containing the ANSI
+// sequences needed to draw the changes since the previous frame, so that the
+// recording plays in any asciicast player.  Resizes are recorded as "r"
+// events and all other input events are recorded as "i" events, using the
+// following data formats:
+//
+//	key <key> <rune> <modifiers> <type> <shifted rune> <base rune>
+//	mouse <x> <y> <buttons> <modifiers>
+//	paste start
+//	paste end
+//
+// Key events recorded as "key <key> <rune> <modifiers>", without the key
+// event type and alternate runes, are replayed as key presses.
+const (
+	AsciicastVersion = 2
+)
+
+var (
+	// ReplayStartTimeout is how long a Replay will wait for the display
+	// manager to start running before giving up
+	ReplayStartTimeout = time.Second * 5
+)
+
+type asciicastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// RecordingDisplay is a Display that records everything rendered and every
+// input event to an asciicast v2 stream, while otherwise passing all calls
+// through to the Display it wraps.
+type RecordingDisplay interface {
+	Display
+
+	// Unwrap returns the Display being recorded.
+	Unwrap() Display
+}
+
+type CRecordingDisplay struct {
+	Display
+
+	out    io.Writer
+	title  string
+	start  time.Time
+	header bool
+	frame  []screenCell
+	frameW int
+	frameH int
+	err    error
+
+	sync.Mutex
+}
+
+type screenCell struct {
+	mainc rune
+	combc []rune
+	style Style
+	width int
+}
+
+func (c screenCell) equals(o screenCell) bool {
+	if c.mainc != o.mainc || c.style != o.style || c.width != o.width {
+		return false
+	}
+	if len(c.combc) != len(o.combc) {
+		return false
+	}
+	for i := range c.combc {
+		if c.combc[i] != o.combc[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// NewRecordingDisplay returns a RecordingDisplay that wraps the given
+// display, writing the recording to out.  Timestamps within the recording
+// are relative to when NewRecordingDisplay is called.
+func NewRecordingDisplay(display Display, title string, out io.Writer) *CRecordingDisplay {
+	return &CRecordingDisplay{
+		Display: display,
+		out:     out,
+		title:   title,
+		start:   time.Now(),
+	}
+}
+
+func (r *CRecordingDisplay) Unwrap() Display {
+	return r.Display
+}
+
+// return the first error encountered writing the recording, if any
+func (r *CRecordingDisplay) Err() error {
+	r.Lock()
+	defer r.Unlock()
+	return r.err
+}
+
+func (r *CRecordingDisplay) Show() {
+	r.Display.Show()
+	r.recordFrame(false)
+}
+
+func (r *CRecordingDisplay) Sync() {
+	r.Display.Sync()
+	r.recordFrame(true)
+}
+
+func (r *CRecordingDisplay) PollEvent() Event {
+	evt := r.Display.PollEvent()
+	switch e := evt.(type) {
+	case *EventResize:
+		w, h := e.Size()
+		r.Lock()
+		r.writeEvent("r", fmt.Sprintf("%dx%d", w, h))
+		r.Unlock()
+	case *EventKey, *EventMouse, *EventPaste:
+		r.Lock()
+		r.writeEvent("i", encodeRecordedEvent(e))
+		r.Unlock()
+	}
+	return evt
+}
+
+func (r *CRecordingDisplay) recordFrame(full bool) {
+	r.Lock()
+	defer r.Unlock()
+	w, h := r.Display.Size()
+	if w != r.frameW || h != r.frameH || r.frame == nil {
+		r.frame = make([]screenCell, w*h)
+		r.frameW, r.frameH = w, h
+		full = true
+	}
+	buf := &bytes.Buffer{}
+	if full {
+		buf.WriteString("\x1b[0m\x1b[2J")
+	}
+	var style *Style
+	lx, ly := -1, -1
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			mainc, combc, s, width := r.Display.GetContent(x, y)
+			cell := screenCell{mainc: mainc, combc: combc, style: s, width: width}
+			idx := (y * w) + x
+			if !full && cell.equals(r.frame[idx]) {
+				if width > 1 {
+					x += width - 1
+				}
+				continue
+			}
+			r.frame[idx] = cell
+			if lx != x || ly != y {
+				buf.WriteString(fmt.Sprintf("\x1b[%d;%dH", y+1, x+1))
+			}
+			if style == nil || *style != s {
+				buf.WriteString(ansiStyle(s))
+				style = &s
+			}
+			if mainc == 0 {
+				mainc = ' '
+			}
+			buf.WriteRune(mainc)
+			for _, c := range combc {
+				buf.WriteRune(c)
+			}
+			if width < 1 {
+				width = 1
+			}
+			lx, ly = x+width, y
+			x += width - 1
+		}
+	}
+	if buf.Len() == 0 {
+		return
+	}
+	buf.WriteString("\x1b[0m")
+	r.writeEvent("o", buf.String())
+}
+
+func (r *CRecordingDisplay) writeHeader() {
+	if r.header {
+		return
+	}
+	r.header = true
+	w, h := r.Display.Size()
+	header := asciicastHeader{
+		Version:   AsciicastVersion,
+		Width:     w,
+		Height:    h,
+		Timestamp: r.start.Unix(),
+		Title:     r.title,
+		Env:       map[string]string{"TERM": os.Getenv("TERM")},
+	}
+	r.writeLine(header)
+}
+
+func (r *CRecordingDisplay) writeEvent(code, data string) {
+	r.writeHeader()
+	at := time.Since(r.start).Seconds()
+	r.writeLine([]interface{}{at, code, data})
+}
+
+func (r *CRecordingDisplay) writeLine(v interface{}) {
+	if r.err != nil {
+		return
+	}
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		r.err = err
+		return
+	}
+	if _, err := r.out.Write(buf.Bytes()); err != nil {
+		ErrorF("error writing session recording: %v", err)
+		r.err = err
+	}
+}
+
+// return the SGR sequence that sets the given style
+func ansiStyle(s Style) string {
+	fg, bg, attrs := s.Decompose()
+	codes := []string{"0"}
+	if attrs.IsBold() {
+		codes = append(codes, "1")
+	}
+	if attrs.IsDim() {
+		codes = append(codes, "2")
+	}
+	if attrs&AttrItalic != 0 {
+		codes = append(codes, "3")
+	}
+	if attrs.IsUnderline() {
+		codes = append(codes, "4")
+	}
+	if attrs.IsBlink() {
+		codes = append(codes, "5")
+	}
+	if attrs.IsReverse() {
+		codes = append(codes, "7")
+	}
+	if attrs&AttrStrikeThrough != 0 {
+		codes = append(codes, "9")
+	}
+	if c := ansiColor(fg, 30); c != "" {
+		codes = append(codes, c)
+	}
+	if c := ansiColor(bg, 40); c != "" {
+		codes = append(codes, c)
+	}
+	return "\x1b[" + strings.Join(codes, ";") + "m"
+}
+
+// return the SGR parameters for the given color, base is 30 for foreground
+// colors and 40 for background colors
+func ansiColor(c Color, base int) string {
+	if !c.Valid() || c == ColorReset {
+		return ""
+	}
+	if c&ColorIsRGB == 0 {
+		if idx := int(c - ColorValid); idx >= 0 && idx < 256 {
+			switch {
+			case idx < 8:
+				return fmt.Sprintf("%d", base+idx)
+			case idx < 16:
+				return fmt.Sprintf("%d", base+60+idx-8)
+			default:
+				return fmt.Sprintf("%d;5;%d", base+8, idx)
+			}
+		}
+	}
+	red, green, blue := c.RGB()
+	if red < 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d;2;%d;%d;%d", base+8, red, green, blue)
+}
+
+func encodeRecordedEvent(evt Event) string {
+	switch e := evt.(type) {
+	case *EventKey:
+		return fmt.Sprintf("key %d %d %d %d %d %d", e.Key(), e.Rune(), e.Modifiers(),
+			e.EventType(), e.ShiftedRune(), e.BaseRune())
+	case *EventMouse:
+		x, y := e.Position()
+		return fmt.Sprintf("mouse %d %d %d %d", x, y, e.Buttons(), e.Modifiers())
+	case *EventPaste:
+		if e.Start() {
+			return "paste start"
+		}
+		return "paste end"
+	}
+	return ""
+}
+
+// ReplayEvent is an input event read from a session recording, along with
+// when it happened relative to the start of the recording.
+type ReplayEvent struct {
+	At    time.Duration
+	Event Event
+}
+
+// Replay is a loaded session recording that can be played back into a
+// DisplayManager.
+type Replay interface {
+	Size() (w, h int)
+	Title() string
+	Events() []ReplayEvent
+	Play(d DisplayManager, speed float64) error
+}
+
+type CReplay struct {
+	width  int
+	height int
+	title  string
+	events []ReplayEvent
+}
+
+// LoadReplayFile reads the asciicast v2 session recording at the given path.
+func LoadReplayFile(path string) (*CReplay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadReplay(f)
+}
+
+// LoadReplay reads an asciicast v2 session recording, keeping only the input
+// and resize events.  Output events are ignored.
+func LoadReplay(in io.Reader) (*CReplay, error) {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("missing asciicast header")
+	}
+	header := asciicastHeader{}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return nil, fmt.Errorf("invalid asciicast header: %v", err)
+	}
+	if header.Version != AsciicastVersion {
+		return nil, fmt.Errorf("unsupported asciicast version: %d", header.Version)
+	}
+	r := &CReplay{
+		width:  header.Width,
+		height: header.Height,
+		title:  header.Title,
+	}
+	var pem *EventMouse
+	line := 1
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var record []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if len(record) != 3 {
+			return nil, fmt.Errorf("line %d: expected 3 fields, found %d", line, len(record))
+		}
+		at, ok := record[0].(float64)
+		code, ok2 := record[1].(string)
+		data, ok3 := record[2].(string)
+		if !ok || !ok2 || !ok3 {
+			return nil, fmt.Errorf("line %d: malformed event", line)
+		}
+		var evt Event
+		switch code {
+		case "r":
+			var w, h int
+			if _, err := fmt.Sscanf(data, "%dx%d", &w, &h); err != nil {
+				return nil, fmt.Errorf("line %d: invalid resize: %q", line, data)
+			}
+			evt = NewEventResize(w, h)
+		case "i":
+			var err error
+			if evt, err = decodeRecordedEvent(data, pem); err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			if em, ok := evt.(*EventMouse); ok {
+				pem = em
+			}
+		default:
+			continue
+		}
+		r.events = append(r.events, ReplayEvent{
+			At:    time.Duration(at * float64(time.Second)),
+			Event: evt,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func decodeRecordedEvent(data string, pem *EventMouse) (Event, error) {
+	fields := strings.Fields(data)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty input event")
+	}
+	switch fields[0] {
+	case "key":
+		var k Key
+		var ch rune
+		var mod ModMask
+		if len(fields) == 4 {
+			// recorded before key event types, shifted and base runes
+			if _, err := fmt.Sscanf(data, "key %d %d %d", &k, &ch, &mod); err != nil {
+				return nil, fmt.Errorf("invalid key event: %q", data)
+			}
+			return NewEventKey(k, ch, mod), nil
+		}
+		var event KeyEventType
+		var shifted, base rune
+		if _, err := fmt.Sscanf(data, "key %d %d %d %d %d %d", &k, &ch, &mod, &event, &shifted, &base); err != nil {
+			return nil, fmt.Errorf("invalid key event: %q", data)
+		}
+		ev := NewEventKeyType(k, ch, mod, event)
+		ev.shifted = shifted
+		ev.base = base
+		return ev, nil
+	case "mouse":
+		var x, y int
+		var btn ButtonMask
+		var mod ModMask
+		if _, err := fmt.Sscanf(data, "mouse %d %d %d %d", &x, &y, &btn, &mod); err != nil {
+			return nil, fmt.Errorf("invalid mouse event: %q", data)
+		}
+		return newEventMouse(pem, x, y, btn, mod), nil
+	case "paste":
+		if len(fields) == 2 {
+			switch fields[1] {
+			case "start":
+				return NewEventPaste(true), nil
+			case "end":
+				return NewEventPaste(false), nil
+			}
+		}
+		return nil, fmt.Errorf("invalid paste event: %q", data)
+	}
+	return nil, fmt.Errorf("unknown input event: %q", data)
+}
+
+func (r *CReplay) Size() (w, h int) {
+	return r.width, r.height
+}
+
+func (r *CReplay) Title() string {
+	return r.title
+}
+
+func (r *CReplay) Events() []ReplayEvent {
+	return r.events
+}
+
+// Play posts the recorded events to the given display manager, waiting for
+// it to start running first.  Events are posted with their original timing
+// divided by speed, so a speed of 2 replays twice as fast.  A speed of zero
+// or less posts all events without any delay.  Play returns once all events
+// are posted or the display manager stops running.
+func (r *CReplay) Play(d DisplayManager, speed float64) error {
+	for waited := time.Duration(0); !d.IsRunning(); waited += time.Millisecond * 10 {
+		if waited >= ReplayStartTimeout {
+			return fmt.Errorf("display manager not running")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	start := time.Now()
+	for _, re := range r.events {
+		if speed > 0 {
+			due := time.Duration(float64(re.At) / speed)
+			if delay := due - time.Since(start); delay > 0 {
+				time.Sleep(delay)
+			}
+		}
+		if !d.IsRunning() {
+			return nil
+		}
+		if err := d.PostEvent(re.Event); err != nil {
+			if !d.IsRunning() {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
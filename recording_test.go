@@ -0,0 +1,185 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRecording(t *testing.T) {
+	Convey("Recording a display session", t, func() {
+		o, err := MakeOffscreenDisplay("")
+		So(err, ShouldBeNil)
+		buf := &bytes.Buffer{}
+		r := NewRecordingDisplay(o, "testing", buf)
+		So(r.Unwrap(), ShouldEqual, o)
+		defer r.Close()
+
+		r.SetContent(0, 0, 'h', nil, StyleDefault)
+		r.SetContent(1, 0, 'i', nil, StyleDefault.Bold(true).Foreground(ColorRed))
+		r.Show()
+		o.InjectKey(KeyRune, 'q', ModAlt)
+		o.InjectMouse(2, 3, Button1, ModNone)
+		o.InjectMouse(2, 3, ButtonNone, ModNone)
+		_ = o.PostEvent(NewEventPaste(true))
+		_ = o.PostEvent(NewEventPaste(false))
+		_ = o.PostEvent(NewEventResize(40, 10))
+		for i := 0; i < 6; i++ {
+			So(r.PollEvent(), ShouldNotBeNil)
+		}
+		So(r.Err(), ShouldBeNil)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		So(len(lines), ShouldEqual, 8)
+		header := asciicastHeader{}
+		So(json.Unmarshal([]byte(lines[0]), &header), ShouldBeNil)
+		So(header.Version, ShouldEqual, 2)
+		So(header.Width, ShouldEqual, 80)
+		So(header.Height, ShouldEqual, 25)
+		So(header.Title, ShouldEqual, "testing")
+		var frame []interface{}
+		So(json.Unmarshal([]byte(lines[1]), &frame), ShouldBeNil)
+		So(frame[1], ShouldEqual, "o")
+		So(frame[2], ShouldContainSubstring, "h\x1b[0;1;91mi")
+
+		Convey("a second frame only draws the changes", func() {
+			r.SetContent(1, 0, 'o', nil, StyleDefault)
+			r.Show()
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			So(json.Unmarshal([]byte(lines[len(lines)-1]), &frame), ShouldBeNil)
+			So(frame[1], ShouldEqual, "o")
+			So(frame[2], ShouldEqual, "\x1b[1;2H\x1b[0mo\x1b[0m")
+		})
+
+		Convey("the recording can be loaded for replay", func() {
+			replay, err := LoadReplay(bytes.NewReader(buf.Bytes()))
+			So(err, ShouldBeNil)
+			So(replay.Title(), ShouldEqual, "testing")
+			w, h := replay.Size()
+			So(w, ShouldEqual, 80)
+			So(h, ShouldEqual, 25)
+			events := replay.Events()
+			So(events, ShouldHaveLength, 6)
+			key, ok := events[0].Event.(*EventKey)
+			So(ok, ShouldBeTrue)
+			So(key.Rune(), ShouldEqual, 'q')
+			So(key.Modifiers(), ShouldEqual, ModAlt)
+			press, ok := events[1].Event.(*EventMouse)
+			So(ok, ShouldBeTrue)
+			So(press.State(), ShouldEqual, BUTTON_PRESS)
+			release, ok := events[2].Event.(*EventMouse)
+			So(ok, ShouldBeTrue)
+			So(release.State(), ShouldEqual, BUTTON_RELEASE)
+			So(events[3].Event.(*EventPaste).Start(), ShouldBeTrue)
+			So(events[4].Event.(*EventPaste).End(), ShouldBeTrue)
+			rw, rh := events[5].Event.(*EventResize).Size()
+			So(rw, ShouldEqual, 40)
+			So(rh, ShouldEqual, 10)
+			for i := 1; i < len(events); i++ {
+				So(events[i].At, ShouldBeGreaterThanOrEqualTo, events[i-1].At)
+			}
+		})
+	})
+
+	Convey("Recording key releases and alternate keys", t, func() {
+		o, err := MakeOffscreenDisplay("")
+		So(err, ShouldBeNil)
+		buf := &bytes.Buffer{}
+		r := NewRecordingDisplay(o, "keys", buf)
+		defer r.Close()
+		release := NewEventKeyType(KeyRune, 'A', ModShift, KeyEventRelease)
+		release.shifted = 'A'
+		release.base = 'a'
+		_ = o.PostEvent(release)
+		So(r.PollEvent(), ShouldEqual, release)
+
+		replay, err := LoadReplay(bytes.NewReader(buf.Bytes()))
+		So(err, ShouldBeNil)
+		events := replay.Events()
+		So(events, ShouldHaveLength, 1)
+		key, ok := events[0].Event.(*EventKey)
+		So(ok, ShouldBeTrue)
+		So(key.Key(), ShouldEqual, KeyRune)
+		So(key.Rune(), ShouldEqual, 'A')
+		So(key.Modifiers(), ShouldEqual, ModShift)
+		So(key.EventType(), ShouldEqual, KeyEventRelease)
+		So(key.ShiftedRune(), ShouldEqual, 'A')
+		So(key.BaseRune(), ShouldEqual, 'a')
+	})
+
+	Convey("Loading invalid recordings", t, func() {
+		_, err := LoadReplay(strings.NewReader(""))
+		So(err, ShouldNotBeNil)
+		_, err = LoadReplay(strings.NewReader(`{"version": 1, "width": 80, "height": 25}`))
+		So(err, ShouldNotBeNil)
+		_, err = LoadReplay(strings.NewReader("{\"version\": 2, \"width\": 80, \"height\": 25}\n[0.1, \"i\", \"bogus\"]\n"))
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldStartWith, "line 2:")
+	})
+
+	Convey("Replaying a recording into a display manager", t, func() {
+		recording := strings.Join([]string{
+			`{"version": 2, "width": 80, "height": 25}`,
+			`[0.001, "o", "ignored"]`,
+			`[0.002, "i", "key 256 97 0"]`,
+			`[0.003, "i", "key 256 98 0"]`,
+		}, "\n")
+		replay, err := LoadReplay(strings.NewReader(recording))
+		So(err, ShouldBeNil)
+		So(replay.Events(), ShouldHaveLength, 2)
+
+		d := NewDisplayManager("replay", OffscreenDisplayTtyPath)
+		defer d.Destroy()
+		d.CaptureDisplay(OffscreenDisplayTtyPath)
+		var lock sync.Mutex
+		var keys []rune
+		d.Connect(SignalEventKey, "replay-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+			if e, ok := argv[1].(*EventKey); ok {
+				lock.Lock()
+				keys = append(keys, e.Rune())
+				lock.Unlock()
+			}
+			return EVENT_PASS
+		})
+		stopped := make(chan struct{})
+		go func() {
+			_ = d.Run()
+			close(stopped)
+		}()
+		So(replay.Play(d, 0), ShouldBeNil)
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			lock.Lock()
+			n := len(keys)
+			lock.Unlock()
+			if n == 2 {
+				break
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+		d.RequestQuit()
+		<-stopped
+		lock.Lock()
+		So(string(keys), ShouldEqual, "ab")
+		lock.Unlock()
+	})
+}
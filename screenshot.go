@@ -0,0 +1,323 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type ScreenshotFormat string
+
+const (
+	ScreenshotAnsi ScreenshotFormat = "ansi"
+	ScreenshotHtml ScreenshotFormat = "html"
+	ScreenshotSvg  ScreenshotFormat = "svg"
+)
+
+var (
+	// ScreenshotKey is the key that saves a screenshot when the display
+	// manager has a screenshot path set
+	ScreenshotKey = KeyF12
+	// colors used for cells with the default foreground and background
+	ScreenshotForeground = ColorSilver
+	ScreenshotBackground = ColorBlack
+	// size of SVG character cells and text, in pixels
+	ScreenshotCellWidth  = 8.4
+	ScreenshotCellHeight = 17.0
+	ScreenshotFontSize   = 14.0
+)
+
+// ScreenshotFormatFromPath returns the screenshot format for the given file
+// path, based on the file extension.  ANSI screenshots use .ans, .ansi or
+// .txt, HTML use .html or .htm and SVG use .svg.
+func ScreenshotFormatFromPath(path string) (ScreenshotFormat, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ans", ".ansi", ".txt":
+		return ScreenshotAnsi, nil
+	case ".html", ".htm":
+		return ScreenshotHtml, nil
+	case ".svg":
+		return ScreenshotSvg, nil
+	}
+	return "", fmt.Errorf("unknown screenshot format for path: %v", path)
+}
+
+// Screenshot is a copy of the contents of a display at a point in time,
+// along with the cursor, which can be written out in a number of formats.
+// The cell under a visible cursor is drawn with reverse video in all
+// formats.  Hyperlinks are dropped, the text of links is written as any
+// other.
+type Screenshot interface {
+	Size() (w, h int)
+	GetContent(x, y int) (mainc rune, combc []rune, style Style, width int)
+	GetCursor() (x, y int, visible bool)
+	Write(w io.Writer, format ScreenshotFormat) error
+	WriteAnsi(w io.Writer) error
+	WriteHtml(w io.Writer) error
+	WriteSvg(w io.Writer) error
+	Save(path string) error
+}
+
+type CScreenshot struct {
+	width     int
+	height    int
+	cells     []screenCell
+	cursorX   int
+	cursorY   int
+	cursorVis bool
+}
+
+// TakeScreenshot copies the current contents of the display, as would be
+// displayed by the next call to Show, and the cursor.
+func TakeScreenshot(display Display) *CScreenshot {
+	if r, ok := display.(RecordingDisplay); ok {
+		display = r.Unwrap()
+	}
+	s := &CScreenshot{cursorX: -1, cursorY: -1}
+	s.width, s.height = display.Size()
+	s.cells = make([]screenCell, s.width*s.height)
+	for y := 0; y < s.height; y++ {
+		for x := 0; x < s.width; x++ {
+			mainc, combc, style, width := display.GetContent(x, y)
+			s.cells[(y*s.width)+x] = screenCell{mainc: mainc, combc: combc, style: style, width: width}
+		}
+	}
+	if c, ok := display.(interface {
+		GetCursor() (x int, y int, visible bool)
+	}); ok {
+		s.cursorX, s.cursorY, s.cursorVis = c.GetCursor()
+	}
+	return s
+}
+
+func (s *CScreenshot) Size() (w, h int) {
+	return s.width, s.height
+}
+
+func (s *CScreenshot) GetContent(x, y int) (mainc rune, combc []rune, style Style, width int) {
+	if x >= 0 && y >= 0 && x < s.width && y < s.height {
+		c := s.cells[(y*s.width)+x]
+		mainc, combc, style, width = c.mainc, c.combc, c.style, c.width
+	}
+	return
+}
+
+func (s *CScreenshot) GetCursor() (x, y int, visible bool) {
+	return s.cursorX, s.cursorY, s.cursorVis
+}
+
+// write the screenshot to the given writer in the format given
+func (s *CScreenshot) Write(w io.Writer, format ScreenshotFormat) error {
+	switch format {
+	case ScreenshotAnsi:
+		return s.WriteAnsi(w)
+	case ScreenshotHtml:
+		return s.WriteHtml(w)
+	case ScreenshotSvg:
+		return s.WriteSvg(w)
+	}
+	return fmt.Errorf("unknown screenshot format: %v", format)
+}
+
+// save the screenshot to the given file path, in the format indicated by
+// the file extension
+func (s *CScreenshot) Save(path string) error {
+	format, err := ScreenshotFormatFromPath(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := s.Write(f, format); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// screenshotRun is a span of cells on one row that share the same style
+type screenshotRun struct {
+	x     int
+	cells int
+	style Style
+	text  string
+}
+
+// return the runs of cells for the given row, cells with a zero width (the
+// second half of wide characters) are skipped
+func (s *CScreenshot) rowRuns(y int) (runs []screenshotRun) {
+	var run *screenshotRun
+	for x := 0; x < s.width; x++ {
+		c := s.cells[(y*s.width)+x]
+		style := c.style
+		if s.cursorVis && x == s.cursorX && y == s.cursorY {
+			_, _, attrs := style.Decompose()
+			style = style.Reverse(!attrs.IsReverse())
+		}
+		width := c.width
+		if width < 1 {
+			width = 1
+		}
+		if run == nil || run.style != style {
+			if run != nil {
+				runs = append(runs, *run)
+			}
+			run = &screenshotRun{x: x, style: style}
+		}
+		mainc := c.mainc
+		if mainc == 0 {
+			mainc = ' '
+		}
+		run.text += string(mainc) + string(c.combc)
+		run.cells += width
+		x += width - 1
+	}
+	if run != nil {
+		runs = append(runs, *run)
+	}
+	return
+}
+
+// write the screenshot as text with ANSI escape sequences for the styles,
+// one line per row
+func (s *CScreenshot) WriteAnsi(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for y := 0; y < s.height; y++ {
+		for _, run := range s.rowRuns(y) {
+			bw.WriteString(ansiStyle(run.style))
+			bw.WriteString(run.text)
+		}
+		bw.WriteString("\x1b[0m\n")
+	}
+	return bw.Flush()
+}
+
+// return the CSS colors and attributes for the style given, with reverse
+// video applied
+func screenshotColors(style Style) (fg, bg string, attrs AttrMask) {
+	var fc, bc Color
+	fc, bc, attrs = style.Decompose()
+	if !fc.Valid() {
+		fc = ScreenshotForeground
+	}
+	if !bc.Valid() {
+		bc = ScreenshotBackground
+	}
+	if attrs.IsReverse() {
+		fc, bc = bc, fc
+	}
+	return screenshotColor(fc), screenshotColor(bc), attrs
+}
+
+func screenshotColor(c Color) string {
+	if v := c.Hex(); v >= 0 {
+		return fmt.Sprintf("#%06x", v)
+	}
+	return "inherit"
+}
+
+// write the screenshot as a standalone HTML page, with inline styles
+func (s *CScreenshot) WriteHtml(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fg, bg := screenshotColor(ScreenshotForeground), screenshotColor(ScreenshotBackground)
+	bw.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>Screenshot</title>\n</head>\n")
+	bw.WriteString(fmt.Sprintf("<body style=\"margin:0;background-color:%s\">\n", bg))
+	bw.WriteString(fmt.Sprintf("<pre style=\"margin:0;padding:0;font-family:monospace;line-height:1.2;color:%s;background-color:%s\">", fg, bg))
+	for y := 0; y < s.height; y++ {
+		for _, run := range s.rowRuns(y) {
+			rf, rb, attrs := screenshotColors(run.style)
+			css := []string{"color:" + rf, "background-color:" + rb}
+			if attrs.IsBold() {
+				css = append(css, "font-weight:bold")
+			}
+			if attrs&AttrItalic != 0 {
+				css = append(css, "font-style:italic")
+			}
+			if attrs.IsDim() {
+				css = append(css, "opacity:0.6")
+			}
+			if decoration := screenshotDecoration(attrs); decoration != "" {
+				css = append(css, "text-decoration:"+decoration)
+			}
+			bw.WriteString(fmt.Sprintf("<span style=\"%s\">%s</span>", strings.Join(css, ";"), html.EscapeString(run.text)))
+		}
+		bw.WriteString("\n")
+	}
+	bw.WriteString("</pre>\n</body>\n</html>\n")
+	return bw.Flush()
+}
+
+func screenshotDecoration(attrs AttrMask) string {
+	var decorations []string
+	if attrs.IsUnderline() {
+		decorations = append(decorations, "underline")
+	}
+	if attrs&AttrStrikeThrough != 0 {
+		decorations = append(decorations, "line-through")
+	}
+	return strings.Join(decorations, " ")
+}
+
+// write the screenshot as an SVG image, using ScreenshotCellWidth and
+// ScreenshotCellHeight for the size of each character cell
+func (s *CScreenshot) WriteSvg(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	cw, ch := ScreenshotCellWidth, ScreenshotCellHeight
+	width, height := float64(s.width)*cw, float64(s.height)*ch
+	bw.WriteString(fmt.Sprintf(
+		"<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%g\" height=\"%g\" viewBox=\"0 0 %g %g\" font-family=\"monospace\" font-size=\"%g\">\n",
+		width, height, width, height, ScreenshotFontSize,
+	))
+	bw.WriteString(fmt.Sprintf("<rect width=\"100%%\" height=\"100%%\" fill=\"%s\"/>\n", screenshotColor(ScreenshotBackground)))
+	for y := 0; y < s.height; y++ {
+		for _, run := range s.rowRuns(y) {
+			rf, rb, attrs := screenshotColors(run.style)
+			x, top, rw := float64(run.x)*cw, float64(y)*ch, float64(run.cells)*cw
+			if rb != screenshotColor(ScreenshotBackground) {
+				bw.WriteString(fmt.Sprintf("<rect x=\"%g\" y=\"%g\" width=\"%g\" height=\"%g\" fill=\"%s\"/>\n", x, top, rw, ch, rb))
+			}
+			if strings.TrimSpace(run.text) == "" && !attrs.IsUnderline() && attrs&AttrStrikeThrough == 0 {
+				continue
+			}
+			extra := ""
+			if attrs.IsBold() {
+				extra += " font-weight=\"bold\""
+			}
+			if attrs&AttrItalic != 0 {
+				extra += " font-style=\"italic\""
+			}
+			if attrs.IsDim() {
+				extra += " opacity=\"0.6\""
+			}
+			if decoration := screenshotDecoration(attrs); decoration != "" {
+				extra += fmt.Sprintf(" text-decoration=\"%s\"", decoration)
+			}
+			bw.WriteString(fmt.Sprintf(
+				"<text x=\"%g\" y=\"%g\" fill=\"%s\" textLength=\"%g\" lengthAdjust=\"spacingAndGlyphs\" xml:space=\"preserve\"%s>%s</text>\n",
+				x, top+(ch*0.8), rf, rw, extra, html.EscapeString(run.text),
+			))
+		}
+	}
+	bw.WriteString("</svg>\n")
+	return bw.Flush()
+}
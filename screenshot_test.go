@@ -0,0 +1,119 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestScreenshot(t *testing.T) {
+	Convey("Taking screenshots of a display", t, func() {
+		o, err := MakeOffscreenDisplay("")
+		So(err, ShouldBeNil)
+		defer o.Close()
+		o.SetSize(6, 2)
+		o.Clear()
+		o.SetContent(0, 0, '<', nil, StyleDefault)
+		o.SetContent(1, 0, 'b', nil, StyleDefault.Bold(true).Foreground(ColorNavy))
+		o.SetContent(0, 1, 'x', nil, StyleDefault.Background(ColorWhite))
+		o.ShowCursor(2, 1)
+		o.Show()
+
+		s := TakeScreenshot(o)
+		w, h := s.Size()
+		So(w, ShouldEqual, 6)
+		So(h, ShouldEqual, 2)
+		mainc, _, style, _ := s.GetContent(1, 0)
+		So(mainc, ShouldEqual, 'b')
+		So(style, ShouldResemble, StyleDefault.Bold(true).Foreground(ColorNavy))
+		cx, cy, vis := s.GetCursor()
+		So(cx, ShouldEqual, 2)
+		So(cy, ShouldEqual, 1)
+		So(vis, ShouldBeTrue)
+
+		Convey("as ANSI text", func() {
+			buf := &bytes.Buffer{}
+			So(s.Write(buf, ScreenshotAnsi), ShouldBeNil)
+			lines := strings.Split(buf.String(), "\n")
+			So(lines, ShouldHaveLength, 3)
+			So(lines[0], ShouldStartWith, "\x1b[0m<\x1b[0;1;34mb\x1b[0m    ")
+			So(lines[1], ShouldStartWith, "\x1b[0;107mx\x1b[0m \x1b[0;7m \x1b[0m   ")
+		})
+
+		Convey("as HTML", func() {
+			buf := &bytes.Buffer{}
+			So(s.Write(buf, ScreenshotHtml), ShouldBeNil)
+			out := buf.String()
+			So(out, ShouldStartWith, "<!DOCTYPE html>")
+			So(out, ShouldContainSubstring, ">&lt;</span>")
+			So(out, ShouldContainSubstring, "<span style=\"color:#000080;background-color:#000000;font-weight:bold\">b</span>")
+			// the cursor is reverse video
+			So(out, ShouldContainSubstring, "<span style=\"color:#000000;background-color:#c0c0c0\"> </span>")
+		})
+
+		Convey("as SVG", func() {
+			buf := &bytes.Buffer{}
+			So(s.Write(buf, ScreenshotSvg), ShouldBeNil)
+			out := buf.String()
+			So(out, ShouldStartWith, "<svg xmlns=\"http://www.w3.org/2000/svg\"")
+			So(out, ShouldContainSubstring, ">&lt;</text>")
+			So(out, ShouldContainSubstring, "font-weight=\"bold\">b</text>")
+			So(out, ShouldContainSubstring, "fill=\"#ffffff\"/>")
+			So(strings.TrimSpace(out), ShouldEndWith, "</svg>")
+		})
+
+		Convey("formats are chosen by file extension", func() {
+			f, err := ScreenshotFormatFromPath("shot.SVG")
+			So(err, ShouldBeNil)
+			So(f, ShouldEqual, ScreenshotSvg)
+			f, err = ScreenshotFormatFromPath("shot.htm")
+			So(err, ShouldBeNil)
+			So(f, ShouldEqual, ScreenshotHtml)
+			f, err = ScreenshotFormatFromPath("shot.ans")
+			So(err, ShouldBeNil)
+			So(f, ShouldEqual, ScreenshotAnsi)
+			_, err = ScreenshotFormatFromPath("shot.png")
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Saving screenshots with the screenshot key", t, WithDisplayManager(func(d DisplayManager) {
+		dir, err := ioutil.TempDir("", "cdk-screenshot")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "shot.html")
+		cd := d.(*CDisplayManager)
+		cd.SetScreenshotPath(path)
+		So(d.ProcessEvent(NewEventKey(ScreenshotKey, 0, ModNone)), ShouldEqual, EVENT_STOP)
+		So(d.ProcessEvent(NewEventKey(ScreenshotKey, 0, ModNone)), ShouldEqual, EVENT_STOP)
+		_, err = os.Stat(path)
+		So(err, ShouldBeNil)
+		_, err = os.Stat(filepath.Join(dir, "shot-2.html"))
+		So(err, ShouldBeNil)
+		// repeats and releases are swallowed without another screenshot
+		So(d.ProcessEvent(NewEventKeyType(ScreenshotKey, 0, ModNone, KeyEventRepeat)), ShouldEqual, EVENT_STOP)
+		So(d.ProcessEvent(NewEventKeyType(ScreenshotKey, 0, ModNone, KeyEventRelease)), ShouldEqual, EVENT_STOP)
+		_, err = os.Stat(filepath.Join(dir, "shot-3.html"))
+		So(os.IsNotExist(err), ShouldBeTrue)
+		So(d.SaveScreenshot(filepath.Join(dir, "shot.bmp")), ShouldNotBeNil)
+	}))
+}
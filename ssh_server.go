@@ -0,0 +1,289 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"io"
+	"net"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SshServer serves one CDK session per SSH connection.  Every session that
+// requests a pty and a shell is given its own DisplayManager, rendering to
+// the SSH channel, and the DisplayInitFn is called to set up the user
+// interface for that session just as CApp does for a local terminal.
+type SshServer interface {
+	ListenAndServe(addr string) error
+	Serve(listener net.Listener) error
+	Sessions() []DisplayManager
+	Close() error
+}
+
+type CSshServer struct {
+	title    string
+	config   *ssh.ServerConfig
+	initFn   DisplayInitFn
+	listener net.Listener
+	sessions []*CDisplayManager
+	closed   bool
+
+	sync.Mutex
+}
+
+// NewSshServer returns a new SshServer using the given server configuration,
+// which must have at least one host key and the authentication methods
+// required.  Each session display manager is given the title and is set up
+// with initFn.
+func NewSshServer(title string, config *ssh.ServerConfig, initFn DisplayInitFn) *CSshServer {
+	return &CSshServer{
+		title:  title,
+		config: config,
+		initFn: initFn,
+	}
+}
+
+// listen on the TCP network address given and Serve incoming connections
+func (s *CSshServer) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// accept connections on the given listener, starting a new CDK session for
+// each shell requested.  Serve always returns a non-nil error unless Close
+// was called.
+func (s *CSshServer) Serve(listener net.Listener) error {
+	s.Lock()
+	s.listener = listener
+	s.Unlock()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.Lock()
+			closed := s.closed
+			s.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		go s.handleConn(conn)
+	}
+}
+
+// return the display managers of all currently running sessions
+func (s *CSshServer) Sessions() (sessions []DisplayManager) {
+	s.Lock()
+	defer s.Unlock()
+	for _, d := range s.sessions {
+		sessions = append(sessions, d)
+	}
+	return
+}
+
+// stop accepting connections and ask all running sessions to quit
+func (s *CSshServer) Close() error {
+	s.Lock()
+	s.closed = true
+	listener := s.listener
+	sessions := append([]*CDisplayManager{}, s.sessions...)
+	s.Unlock()
+	for _, d := range sessions {
+		d.RequestQuit()
+	}
+	if listener != nil {
+		return listener.Close()
+	}
+	return nil
+}
+
+func (s *CSshServer) handleConn(conn net.Conn) {
+	sc, channels, requests, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		DebugF("ssh handshake failed for %v: %v", conn.RemoteAddr(), err)
+		_ = conn.Close()
+		return
+	}
+	defer sc.Close()
+	go ssh.DiscardRequests(requests)
+	for nc := range channels {
+		if nc.ChannelType() != "session" {
+			_ = nc.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		channel, channelRequests, err := nc.Accept()
+		if err != nil {
+			ErrorF("ssh session channel not accepted: %v", err)
+			continue
+		}
+		go s.handleSession(channel, channelRequests)
+	}
+}
+
+// payload of a "pty-req" session request, RFC 4254 section 6.2
+type sshPtyRequest struct {
+	Term     string
+	Columns  uint32
+	Rows     uint32
+	PxWidth  uint32
+	PxHeight uint32
+	Modes    string
+}
+
+// payload of a "window-change" session request, RFC 4254 section 6.7
+type sshWindowChange struct {
+	Columns  uint32
+	Rows     uint32
+	PxWidth  uint32
+	PxHeight uint32
+}
+
+// payload of an "exit-status" session request, RFC 4254 section 6.10
+type sshExitStatus struct {
+	Status uint32
+}
+
+// sshSessionStream is the session channel as seen by the display, noting
+// when the client has closed its side of the channel
+type sshSessionStream struct {
+	ssh.Channel
+	eof  chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+func newSshSessionStream(channel ssh.Channel) *sshSessionStream {
+	return &sshSessionStream{
+		Channel: channel,
+		eof:     make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+func (s *sshSessionStream) Read(data []byte) (int, error) {
+	n, err := s.Channel.Read(data)
+	if err == io.EOF {
+		s.once.Do(func() { close(s.eof) })
+	}
+	return n, err
+}
+
+func (s *CSshServer) handleSession(channel ssh.Channel, requests <-chan *ssh.Request) {
+	var pty *sshPtyRequest
+	var display StreamDisplay
+	for req := range requests {
+		switch req.Type {
+		case "pty-req":
+			p := &sshPtyRequest{}
+			if err := ssh.Unmarshal(req.Payload, p); err != nil || display != nil {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			pty = p
+			_ = req.Reply(true, nil)
+		case "window-change":
+			wc := &sshWindowChange{}
+			if err := ssh.Unmarshal(req.Payload, wc); err != nil {
+				continue
+			}
+			if display != nil {
+				display.SetWindowSize(int(wc.Columns), int(wc.Rows))
+			} else if pty != nil {
+				pty.Columns, pty.Rows = wc.Columns, wc.Rows
+			}
+		case "shell":
+			if pty == nil || display != nil {
+				// without a pty there is nothing to render to
+				_ = req.Reply(false, nil)
+				continue
+			}
+			var err error
+			stream := newSshSessionStream(channel)
+			if display, err = NewStreamDisplay(pty.Term, stream); err != nil {
+				ErrorF("ssh session display for %v: %v", pty.Term, err)
+				_ = req.Reply(false, nil)
+				continue
+			}
+			display.SetWindowSize(int(pty.Columns), int(pty.Rows))
+			_ = req.Reply(true, nil)
+			go s.runSession(stream, display)
+		default:
+			if req.WantReply {
+				_ = req.Reply(false, nil)
+			}
+		}
+	}
+}
+
+func (s *CSshServer) runSession(stream *sshSessionStream, display StreamDisplay) {
+	channel := stream.Channel
+	defer channel.Close()
+	defer close(stream.done)
+	status := &sshExitStatus{}
+	if err := display.Init(); err != nil {
+		ErrorF("ssh session display init: %v", err)
+		status.Status = 1
+		_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(status))
+		return
+	}
+	d := NewDisplayManager(s.title, "")
+	d.initFn = s.initFn
+	d.captureDisplay(display)
+	if !s.addSession(d) {
+		d.ReleaseDisplay()
+		d.Destroy()
+		return
+	}
+	go func() {
+		// the client went away, there is no more input coming
+		select {
+		case <-stream.eof:
+			d.RequestQuit()
+		case <-stream.done:
+		}
+	}()
+	if err := d.Run(); err != nil {
+		d.LogErr(err)
+		status.Status = 1
+	}
+	s.removeSession(d)
+	d.Destroy()
+	_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(status))
+}
+
+func (s *CSshServer) addSession(d *CDisplayManager) bool {
+	s.Lock()
+	defer s.Unlock()
+	if s.closed {
+		return false
+	}
+	s.sessions = append(s.sessions, d)
+	return true
+}
+
+func (s *CSshServer) removeSession(d *CDisplayManager) {
+	s.Lock()
+	defer s.Unlock()
+	for idx, sd := range s.sessions {
+		if sd == d {
+			s.sessions = append(s.sessions[:idx], s.sessions[idx+1:]...)
+			return
+		}
+	}
+}
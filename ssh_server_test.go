@@ -0,0 +1,127 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testSshOutput struct {
+	buf bytes.Buffer
+
+	sync.Mutex
+}
+
+func (o *testSshOutput) Write(p []byte) (int, error) {
+	o.Lock()
+	defer o.Unlock()
+	return o.buf.Write(p)
+}
+
+func (o *testSshOutput) Contains(text string) bool {
+	o.Lock()
+	defer o.Unlock()
+	return bytes.Contains(o.buf.Bytes(), []byte(text))
+}
+
+func waitForTestSsh(timeout time.Duration, fn func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if fn() {
+			return true
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	return false
+}
+
+func TestSshServer(t *testing.T) {
+	Convey("Serving CDK sessions over SSH", t, func() {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		So(err, ShouldBeNil)
+		signer, err := ssh.NewSignerFromKey(key)
+		So(err, ShouldBeNil)
+		config := &ssh.ServerConfig{NoClientAuth: true}
+		config.AddHostKey(signer)
+
+		s := NewSshServer("testing", config, func(d DisplayManager) error {
+			w := NewWindow("ssh-window", d)
+			w.Connect(SignalDraw, "ssh-test-draw", func(_ []interface{}, argv ...interface{}) EventFlag {
+				if canvas, ok := argv[1].(Canvas); ok {
+					canvas.DrawSingleLineText(MakePoint2I(0, 0), 20, false, JUSTIFY_LEFT, StyleDefault, false, "hello-ssh")
+				}
+				return EVENT_STOP
+			})
+			w.Connect(SignalEvent, "ssh-test-event", func(_ []interface{}, argv ...interface{}) EventFlag {
+				return EVENT_STOP
+			})
+			d.SetActiveWindow(w)
+			return nil
+		})
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		served := make(chan error, 1)
+		go func() {
+			served <- s.Serve(listener)
+		}()
+
+		client, err := ssh.Dial("tcp", listener.Addr().String(), &ssh.ClientConfig{
+			User:            "tester",
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		})
+		So(err, ShouldBeNil)
+		defer client.Close()
+
+		session, err := client.NewSession()
+		So(err, ShouldBeNil)
+		output := &testSshOutput{}
+		session.Stdout = output
+		stdin, err := session.StdinPipe()
+		So(err, ShouldBeNil)
+		So(session.RequestPty("xterm", 10, 40, ssh.TerminalModes{}), ShouldBeNil)
+		So(session.Shell(), ShouldBeNil)
+
+		So(waitForTestSsh(time.Second*2, func() bool { return output.Contains("hello-ssh") }), ShouldBeTrue)
+		So(waitForTestSsh(time.Second, func() bool { return len(s.Sessions()) == 1 }), ShouldBeTrue)
+		sd := s.Sessions()[0]
+		w, h := sd.Display().Size()
+		So(w, ShouldEqual, 40)
+		So(h, ShouldEqual, 10)
+
+		So(session.WindowChange(12, 50), ShouldBeNil)
+		So(waitForTestSsh(time.Second, func() bool {
+			w, h := sd.Display().Size()
+			return w == 50 && h == 12
+		}), ShouldBeTrue)
+
+		_ = stdin.(io.Closer).Close()
+		So(session.Wait(), ShouldBeNil)
+		So(waitForTestSsh(time.Second, func() bool { return len(s.Sessions()) == 0 }), ShouldBeTrue)
+
+		So(s.Close(), ShouldBeNil)
+		So(<-served, ShouldBeNil)
+	})
+}
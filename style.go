@@ -16,6 +16,7 @@ package cdk
 
 import (
 	"fmt"
+	"sync"
 )
 
 // Style represents a complete text style, including both foreground color,
@@ -30,9 +31,20 @@ type Style struct {
 	fg    Color
 	bg    Color
 	attrs AttrMask
+	link  uint32
 }
 
 func (s Style) String() string {
+	if url, id := s.Hyperlink(); url != "" {
+		return fmt.Sprintf(
+			"{fg=%v,bg=%v,attrs=%v,url=%v,id=%v}",
+			s.fg.String(),
+			s.bg.String(),
+			s.attrs,
+			url,
+			id,
+		)
+	}
 	return fmt.Sprintf(
 		"{fg=%v,bg=%v,attrs=%v}",
 		s.fg.String(),
@@ -55,6 +67,7 @@ func (s Style) Foreground(c Color) Style {
 		fg:    c,
 		bg:    s.bg,
 		attrs: s.attrs,
+		link:  s.link,
 	}
 }
 
@@ -65,6 +78,7 @@ func (s Style) Background(c Color) Style {
 		fg:    s.fg,
 		bg:    c,
 		attrs: s.attrs,
+		link:  s.link,
 	}
 }
 
@@ -80,20 +94,23 @@ func (s Style) setAttrs(attrs AttrMask, on bool) Style {
 			fg:    s.fg,
 			bg:    s.bg,
 			attrs: s.attrs | attrs,
+			link:  s.link,
 		}
 	}
 	return Style{
 		fg:    s.fg,
 		bg:    s.bg,
 		attrs: s.attrs &^ attrs,
+		link:  s.link,
 	}
 }
 
 // Normal returns the style with all attributes disabled.
 func (s Style) Normal() Style {
 	return Style{
-		fg: s.fg,
-		bg: s.bg,
+		fg:   s.fg,
+		bg:   s.bg,
+		link: s.link,
 	}
 }
 
@@ -146,5 +163,80 @@ func (s Style) Attributes(attrs AttrMask) Style {
 		fg:    s.fg,
 		bg:    s.bg,
 		attrs: attrs,
+		link:  s.link,
+	}
+}
+
+// Url returns a new style based on s, with the hyperlink target set to the
+// url given.  Displays which support hyperlinks, using OSC 8, make the text
+// drawn with the style clickable.  An empty url removes the hyperlink.  Styles
+// hold an index of the hyperlinks set, which are kept for the life of the
+// process.
+func (s Style) Url(url string) Style {
+	_, id := s.Hyperlink()
+	return Style{
+		fg:    s.fg,
+		bg:    s.bg,
+		attrs: s.attrs,
+		link:  internHyperlink(url, id),
+	}
+}
+
+// UrlId returns a new style based on s, with the hyperlink id set as
+// requested.  Cells with the same url and id are taken to be one hyperlink
+// by the terminal, even when not adjacent, such as a link wrapped over more
+// than one line.
+func (s Style) UrlId(id string) Style {
+	url, _ := s.Hyperlink()
+	return Style{
+		fg:    s.fg,
+		bg:    s.bg,
+		attrs: s.attrs,
+		link:  internHyperlink(url, id),
+	}
+}
+
+// Hyperlink returns the hyperlink target and id of the style, the url is
+// empty for styles without a hyperlink.
+func (s Style) Hyperlink() (url string, id string) {
+	if s.link == 0 {
+		return "", ""
+	}
+	cdkHyperlinksLock.RLock()
+	defer cdkHyperlinksLock.RUnlock()
+	link := cdkHyperlinks[s.link]
+	return link.url, link.id
+}
+
+// hyperlink is the target and id of a hyperlink, styles hold the index of
+// these as interned, such that styles stay small and cheap to compare
+type hyperlink struct {
+	url string
+	id  string
+}
+
+// the hyperlinks interned, the first of which is no hyperlink at all
+var (
+	cdkHyperlinks     = []hyperlink{{}}
+	cdkHyperlinkIndex = map[hyperlink]uint32{{}: 0}
+	cdkHyperlinksLock = &sync.RWMutex{}
+)
+
+// internHyperlink returns the index of the hyperlink given, adding it if new
+func internHyperlink(url, id string) uint32 {
+	link := hyperlink{url: url, id: id}
+	cdkHyperlinksLock.RLock()
+	index, ok := cdkHyperlinkIndex[link]
+	cdkHyperlinksLock.RUnlock()
+	if ok {
+		return index
+	}
+	cdkHyperlinksLock.Lock()
+	defer cdkHyperlinksLock.Unlock()
+	if index, ok = cdkHyperlinkIndex[link]; !ok {
+		index = uint32(len(cdkHyperlinks))
+		cdkHyperlinks = append(cdkHyperlinks, link)
+		cdkHyperlinkIndex[link] = index
 	}
+	return index
 }
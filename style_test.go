@@ -63,4 +63,22 @@ func TestStyle(t *testing.T) {
 		_, _, attr = s7.Decompose()
 		So(attr, ShouldEqual, AttrReverse|AttrBold|AttrDim|AttrItalic|AttrStrikeThrough)
 	})
+	Convey("Style hyperlinks", t, func() {
+		url, id := StyleDefault.Hyperlink()
+		So(url, ShouldEqual, "")
+		So(id, ShouldEqual, "")
+		link := StyleDefault.Url("https://example.com").UrlId("ex")
+		So(link, ShouldNotEqual, StyleDefault)
+		// kept when changing colors and attributes
+		link = link.Foreground(ColorBlue).Background(ColorRed).Bold(true).Normal().Attributes(AttrDim)
+		url, id = link.Hyperlink()
+		So(url, ShouldEqual, "https://example.com")
+		So(id, ShouldEqual, "ex")
+		So(link.String(), ShouldContainSubstring, "url=https://example.com")
+		url, _ = link.Url("").Hyperlink()
+		So(url, ShouldEqual, "")
+		So(hyperlinkSequence("https://example.com/a b", "e;x"), ShouldEqual, "\x1b]8;id=ex;https://example.com/a b\x1b\\")
+		So(hyperlinkSequence("https://example.com/\u00e9", ""), ShouldEqual, "\x1b]8;;https://example.com/%C3%A9\x1b\\")
+		So(hyperlinkSequence("", "ex"), ShouldEqual, hyperlinkClose)
+	})
 }
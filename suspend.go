@@ -0,0 +1,157 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+const (
+	// SignalSuspend is emitted on the display manager before the terminal
+	// is handed back to the user's shell, with the display manager as argv.
+	// Suspending is cancelled if a listener returns EVENT_STOP.
+	SignalSuspend Signal = "suspend"
+	// SignalResume is emitted on the display manager once the terminal has
+	// been taken back after being suspended, with the display manager as
+	// argv.
+	SignalResume Signal = "resume"
+)
+
+// jobControlled is implemented by displays that can be on the terminal the
+// process was started from, see cDisplay
+type jobControlled interface {
+	hasJobControl() bool
+	stopJob() bool
+}
+
+// Suspend hands the terminal back to the user's shell, see Display.Suspend,
+// and stops rendering frames until resumed with Resume.  When the display is
+// on the terminal the process was started from, the process is stopped too,
+// as the shell would on Ctrl+Z, and resumed once continued with SIGCONT, by
+// the shell's fg command for instance.
+//
+// The display manager is also suspended by SIGTSTP, and by Ctrl+Z key
+// presses not handled by the windows or listeners of SignalEventKey, when
+// the display is on the terminal the process was started from.
+func (d *CDisplayManager) Suspend() error {
+	d.Lock()
+	display := d.display
+	d.Unlock()
+	if display == nil {
+		return ErrNoDisplay
+	}
+	d.suspendLock.Lock()
+	if d.suspended {
+		d.suspendLock.Unlock()
+		return nil
+	}
+	d.suspended = true
+	d.suspendLock.Unlock()
+	if f := d.Emit(SignalSuspend, d); f == EVENT_STOP {
+		d.LogTrace("suspend cancelled by a listener")
+		d.cancelSuspend()
+		return nil
+	}
+	d.breakPointerGrab()
+	if err := display.Suspend(); err != nil {
+		d.cancelSuspend()
+		return err
+	}
+	d.LogTrace("display suspended")
+	if job := d.jobControl(); job != nil && job.stopJob() {
+		d.LogTrace("process stopped")
+	}
+	return nil
+}
+
+// Resume takes the terminal back from the user's shell, undoing Suspend, and
+// redraws the whole display.  When the process is continued with SIGCONT the
+// display manager is resumed, whether suspended or stopped by other means,
+// though SignalResume is only emitted if it was suspended.
+func (d *CDisplayManager) Resume() error {
+	d.Lock()
+	display := d.display
+	d.Unlock()
+	if display == nil {
+		return ErrNoDisplay
+	}
+	if err := display.Resume(); err != nil {
+		return err
+	}
+	if d.displayResumed() {
+		d.LogTrace("display resumed")
+		d.Emit(SignalResume, d)
+	}
+	return nil
+}
+
+// cancelSuspend clears the suspended state claimed by Suspend, when the
+// display was not suspended after all
+func (d *CDisplayManager) cancelSuspend() {
+	d.suspendLock.Lock()
+	d.suspended = false
+	d.suspendLock.Unlock()
+}
+
+// displayResumed clears the suspended state once the display has been
+// resumed and requests everything be redrawn, returning true if the display
+// manager was suspended
+func (d *CDisplayManager) displayResumed() (suspended bool) {
+	d.suspendLock.Lock()
+	suspended = d.suspended
+	d.suspended = false
+	d.suspendLock.Unlock()
+	// the user's title was restored when suspended
+	d.titleLock.Lock()
+	d.shownTitle = ""
+	d.titleLock.Unlock()
+	if d.IsRunning() {
+		d.RequestDraw()
+		d.RequestSync()
+	}
+	return
+}
+
+// IsSuspended returns true if the display manager is suspended.
+func (d *CDisplayManager) IsSuspended() bool {
+	d.suspendLock.Lock()
+	defer d.suspendLock.Unlock()
+	return d.suspended
+}
+
+// jobControl returns the display captured if it is on the terminal the
+// process was started from, or nil
+func (d *CDisplayManager) jobControl() jobControlled {
+	d.Lock()
+	display := d.display
+	d.Unlock()
+	if r, ok := display.(RecordingDisplay); ok {
+		display = r.Unwrap()
+	}
+	if job, ok := display.(jobControlled); ok && job.hasJobControl() {
+		return job
+	}
+	return nil
+}
+
+// processSuspend suspends or resumes the display manager for the event given
+func (d *CDisplayManager) processSuspend(e *EventSuspend) EventFlag {
+	var err error
+	if e.Suspend() {
+		err = d.Suspend()
+	} else {
+		err = d.Resume()
+	}
+	if err != nil {
+		d.LogErr(err)
+	}
+	return EVENT_STOP
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDisplayManagerSuspend(t *testing.T) {
+	Convey("Suspending the display manager", t, func() {
+		d := NewDisplayManager("suspend", OffscreenDisplayTtyPath)
+		defer d.Destroy()
+		So(d.Suspend(), ShouldEqual, ErrNoDisplay)
+		d.CaptureDisplay(OffscreenDisplayTtyPath)
+		o := d.Display().(OffscreenDisplay)
+
+		var seen []Signal
+		for _, signal := range []Signal{SignalSuspend, SignalResume} {
+			signal := signal
+			d.Connect(signal, "suspend-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+				seen = append(seen, signal)
+				return EVENT_PASS
+			})
+		}
+
+		Convey("hands the display back until resumed", func() {
+			So(d.Suspend(), ShouldBeNil)
+			So(d.IsSuspended(), ShouldBeTrue)
+			So(o.IsSuspended(), ShouldBeTrue)
+			So(d.Suspend(), ShouldBeNil)
+			So(seen, ShouldResemble, []Signal{SignalSuspend})
+			So(d.Resume(), ShouldBeNil)
+			So(d.IsSuspended(), ShouldBeFalse)
+			So(o.IsSuspended(), ShouldBeFalse)
+			So(seen, ShouldResemble, []Signal{SignalSuspend, SignalResume})
+		})
+
+		Convey("does not show anything while suspended", func() {
+			o.SetSize(3, 1)
+			o.SetContent(0, 0, 'a', nil, StyleDefault)
+			o.Show()
+			So(d.Suspend(), ShouldBeNil)
+			o.SetContent(0, 0, 'b', nil, StyleDefault)
+			o.Show()
+			cells, _, _ := o.GetContents()
+			So(string(cells[0].Runes), ShouldEqual, "a")
+			So(d.Resume(), ShouldBeNil)
+			cells, _, _ = o.GetContents()
+			So(string(cells[0].Runes), ShouldEqual, "b")
+		})
+
+		Convey("can be cancelled by a listener", func() {
+			d.Connect(SignalSuspend, "suspend-test-cancel", func(_ []interface{}, argv ...interface{}) EventFlag {
+				return EVENT_STOP
+			})
+			So(d.Suspend(), ShouldBeNil)
+			So(d.IsSuspended(), ShouldBeFalse)
+			So(o.IsSuspended(), ShouldBeFalse)
+		})
+
+		Convey("follows job control events", func() {
+			d.ProcessEvent(NewEventSuspend(true))
+			So(d.IsSuspended(), ShouldBeTrue)
+			d.ProcessEvent(NewEventSuspend(false))
+			So(d.IsSuspended(), ShouldBeFalse)
+			d.ProcessEvent(NewEventSuspend(false))
+			So(seen, ShouldResemble, []Signal{SignalSuspend, SignalResume})
+		})
+
+		Convey("does not on Ctrl+Z without job control", func() {
+			d.ProcessEvent(NewEventKey(KeyCtrlZ, rune(KeyCtrlZ), ModCtrl))
+			So(d.IsSuspended(), ShouldBeFalse)
+			So(seen, ShouldBeEmpty)
+		})
+	})
+}
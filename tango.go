@@ -31,6 +31,7 @@ package cdk
 <i></i>
 <s></s>
 <u></u>
+<a href=[url] id=[string]></a>
 
 */
 
@@ -97,6 +98,8 @@ func (m *CTango) init() error {
 	mstyle := m.style
 	cstyle := m.style
 	pstyle := m.style
+	// the hyperlinks of the links entered, restored as each is left
+	var links []Style
 
 	isWord := false
 	var err error
@@ -129,6 +132,9 @@ func (m *CTango) init() error {
 				cstyle = cstyle.Underline(true)
 			case "d":
 				cstyle = cstyle.Dim(true)
+			case "a":
+				links = append(links, cstyle)
+				cstyle = m.parseLinkAttrs(cstyle, t.Attr)
 			}
 		case xml.EndElement:
 			switch t.Name.Local {
@@ -146,6 +152,12 @@ func (m *CTango) init() error {
 				cstyle = cstyle.Underline(false)
 			case "d":
 				cstyle = cstyle.Dim(false)
+			case "a":
+				if n := len(links); n > 0 {
+					url, id := links[n-1].Hyperlink()
+					links = links[:n-1]
+					cstyle = cstyle.Url(url).UrlId(id)
+				}
 			}
 		case xml.CharData:
 			content := xml.CharData(t) // CharData []byte
@@ -212,3 +224,16 @@ func (m *CTango) parseStyleAttrs(attrs []xml.Attr) (style Style) {
 	}
 	return
 }
+
+func (m *CTango) parseLinkAttrs(style Style, attrs []xml.Attr) Style {
+	url, id := "", ""
+	for _, attr := range attrs {
+		switch attr.Name.Local {
+		case "href":
+			url = attr.Value
+		case "id":
+			id = attr.Value
+		}
+	}
+	return style.Url(url).UrlId(id)
+}
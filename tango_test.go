@@ -0,0 +1,74 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTangoLinks(t *testing.T) {
+	Convey("Tango hyperlinks", t, func() {
+		m, err := NewMarkup(`see <a href="https://example.com" id="ex">the <b>docs</b></a> now`, StyleDefault)
+		So(err, ShouldBeNil)
+		input := m.(*CTango).input
+		urlOf := func(index int) (url, id string) {
+			return input.GetCharacter(index).Style().Hyperlink()
+		}
+		url, _ := urlOf(0)
+		So(url, ShouldEqual, "")
+		for _, index := range []int{4, 7, 11} {
+			url, id := urlOf(index)
+			So(url, ShouldEqual, "https://example.com")
+			So(id, ShouldEqual, "ex")
+		}
+		_, _, attrs := input.GetCharacter(8).Style().Decompose()
+		So(attrs.IsBold(), ShouldBeTrue)
+		url, _ = urlOf(12)
+		So(url, ShouldEqual, "")
+
+		Convey("nested within others", func() {
+			m, err := NewMarkup(`<a href="https://a.example"><b>a <a href="https://b.example">b</a> a</b></a> c`, StyleDefault)
+			So(err, ShouldBeNil)
+			input := m.(*CTango).input
+			for index, want := range map[int]string{0: "https://a.example", 2: "https://b.example", 4: "https://a.example", 6: ""} {
+				url, _ := input.GetCharacter(index).Style().Hyperlink()
+				So(url, ShouldEqual, want)
+			}
+			_, _, attrs := input.GetCharacter(4).Style().Decompose()
+			So(attrs.IsBold(), ShouldBeTrue)
+		})
+
+		Convey("on the offscreen display", func() {
+			o, err := MakeOffscreenDisplay("")
+			So(err, ShouldBeNil)
+			defer o.Close()
+			o.SetSize(20, 1)
+			for i := 0; i < input.CharacterCount(); i++ {
+				c := input.GetCharacter(i)
+				o.SetContent(i, 0, c.Value(), nil, c.Style())
+			}
+			o.Show()
+			url, id := o.GetHyperlink(4, 0)
+			So(url, ShouldEqual, "https://example.com")
+			So(id, ShouldEqual, "ex")
+			url, _ = o.GetHyperlink(0, 0)
+			So(url, ShouldEqual, "")
+			url, _ = o.GetHyperlink(40, 0)
+			So(url, ShouldEqual, "")
+		})
+	})
+}
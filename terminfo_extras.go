@@ -0,0 +1,64 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"sync"
+)
+
+// terminfoExtras are the capabilities of a terminal that tcell's terminfo
+// does not have
+type terminfoExtras struct {
+	// move to and from the status line, tsl and fsl, if it has one, hs
+	toStatusLine   string
+	fromStatusLine string
+	// set and reset the cursor style, DECSCUSR, the Ss and Se extensions
+	setCursorStyle   string
+	resetCursorStyle string
+	// synchronized updates, the Sync extension
+	syncOutput bool
+}
+
+// the extra capabilities of the terminals built in, as written by ncurses,
+// and those loaded with infocmp, by name
+var (
+	cdkTerminfoExtras = map[string]terminfoExtras{
+		"alacritty":      {setCursorStyle: "\x1b[%p1%d q", resetCursorStyle: "\x1b[0 q"},
+		"st":             {setCursorStyle: "\x1b[%p1%d q", resetCursorStyle: "\x1b[2 q"},
+		"st-256color":    {setCursorStyle: "\x1b[%p1%d q", resetCursorStyle: "\x1b[2 q"},
+		"tmux":           {setCursorStyle: "\x1b[%p1%d q", resetCursorStyle: "\x1b[2 q"},
+		"tmux-256color":  {setCursorStyle: "\x1b[%p1%d q", resetCursorStyle: "\x1b[2 q"},
+		"xterm":          {setCursorStyle: "\x1b[%p1%d q", resetCursorStyle: "\x1b[2 q"},
+		"xterm-88color":  {setCursorStyle: "\x1b[%p1%d q", resetCursorStyle: "\x1b[2 q"},
+		"xterm-256color": {setCursorStyle: "\x1b[%p1%d q", resetCursorStyle: "\x1b[2 q"},
+		"xterm-kitty":    {setCursorStyle: "\x1b[%p1%d q", resetCursorStyle: "\x1b[2 q"},
+	}
+	cdkTerminfoExtrasLock = &sync.Mutex{}
+)
+
+// addTerminfoExtras records the extra capabilities of the named terminal
+func addTerminfoExtras(name string, extras terminfoExtras) {
+	cdkTerminfoExtrasLock.Lock()
+	defer cdkTerminfoExtrasLock.Unlock()
+	cdkTerminfoExtras[name] = extras
+}
+
+// lookupTerminfoExtras returns the extra capabilities of the named terminal,
+// those that are unknown are empty
+func lookupTerminfoExtras(name string) terminfoExtras {
+	cdkTerminfoExtrasLock.Lock()
+	defer cdkTerminfoExtrasLock.Unlock()
+	return cdkTerminfoExtras[name]
+}
@@ -26,3 +26,4 @@ import (
 func loadDynamicTerminfo(_ string) (*terminfo.Terminfo, error) {
 	return nil, errors.New("terminal type unsupported")
 }
+
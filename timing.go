@@ -17,81 +17,159 @@ package cdk
 // TODO: use UUIDs instead of Timer ID numbers
 
 import (
+	"sync"
 	"time"
 )
 
-var cdkTimeouts *timers = &timers{}
+// cdkTimeouts are used for timeouts added when there is no default display
+// manager to add them to
+var cdkTimeouts *timers = newTimers()
+
+// timer IDs are unique across all sets of timers, so that an ID is never
+// mistaken for that of a timer in another set
+var (
+	cdkTimerID     = -1
+	cdkTimerIDLock = &sync.Mutex{}
+)
+
+func nextTimerID() int {
+	cdkTimerIDLock.Lock()
+	defer cdkTimerIDLock.Unlock()
+	cdkTimerID++
+	return cdkTimerID
+}
 
 type timers struct {
-	timers []*timer
+	timers map[int]*timer
+
+	sync.Mutex
+}
+
+func newTimers() *timers {
+	return &timers{
+		timers: make(map[int]*timer),
+	}
 }
 
 func (t *timers) Add(n *timer) (id int) {
-	t.timers = append(t.timers, n)
-	id = len(t.timers) - 1
+	t.Lock()
+	defer t.Unlock()
+	id = nextTimerID()
+	t.timers[id] = n
 	return
 }
 
+func (t *timers) valid(id int) bool {
+	return t.timers[id] != nil
+}
+
 func (t *timers) Valid(id int) bool {
-	if id < len(t.timers) {
-		if t.timers[id] != nil {
-			return true
-		}
-	}
-	return false
+	t.Lock()
+	defer t.Unlock()
+	return t.valid(id)
 }
 
 func (t *timers) Get(id int) *timer {
-	if t.Valid(id) {
-		return t.timers[id]
-	}
-	return nil
+	t.Lock()
+	defer t.Unlock()
+	return t.timers[id]
 }
 
 func (t *timers) Remove(id int) {
-	// retain the timer IDs
-	if t.Valid(id) {
-		t.timers[id] = nil
-	}
+	t.Lock()
+	defer t.Unlock()
+	delete(t.timers, id)
 }
 
 func (t *timers) Stop(id int) bool {
-	if t.Valid(id) {
-		t.timers[id].timer.Stop()
-		t.Remove(id)
+	t.Lock()
+	defer t.Unlock()
+	if t.valid(id) {
+		t.timers[id].stop()
+		delete(t.timers, id)
 		return true
 	}
 	return false
 }
 
+// stop all timers, their IDs are never used again
+func (t *timers) StopAll() {
+	t.Lock()
+	defer t.Unlock()
+	for id, n := range t.timers {
+		n.stop()
+		delete(t.timers, id)
+	}
+}
+
 type timer struct {
-	id    int
-	d     time.Duration
-	fn    TimerCallbackFn
-	timer *time.Timer
+	id     int
+	d      time.Duration
+	fn     TimerCallbackFn
+	timer  *time.Timer
+	owner  *timers
+	halted bool
+
+	sync.Mutex
 }
 
 func (t *timer) handler() {
 	if f := t.fn(); f == EVENT_STOP {
-		cdkTimeouts.Remove(t.id)
+		t.Lock()
+		id := t.id
+		t.Unlock()
+		t.owner.Remove(id)
 	} else {
-		t.timer.Stop()
-		t.timer = time.AfterFunc(t.d, t.handler)
+		t.Lock()
+		if !t.halted {
+			t.timer.Stop()
+			t.timer = time.AfterFunc(t.d, t.handler)
+		}
+		t.Unlock()
 	}
 }
 
+func (t *timer) stop() {
+	t.Lock()
+	defer t.Unlock()
+	t.halted = true
+	t.timer.Stop()
+}
+
 type TimerCallbackFn = func() EventFlag
 
-func AddTimeout(d time.Duration, fn TimerCallbackFn) (id int) {
-	t := &timer{
-		fn: fn,
+// add a timeout to the given set of timers
+func (t *timers) AddTimeout(d time.Duration, fn TimerCallbackFn) (id int) {
+	n := &timer{
+		d:     d,
+		fn:    fn,
+		owner: t,
 	}
-	t.timer = time.AfterFunc(d, t.handler)
-	t.id = cdkTimeouts.Add(t)
-	id = t.id
+	n.Lock()
+	n.timer = time.AfterFunc(d, n.handler)
+	n.id = t.Add(n)
+	n.Unlock()
+	id = n.id
 	return
 }
 
+// AddTimeout calls fn after the given duration, and again after each
+// following duration until fn returns EVENT_STOP. The timeout is added to
+// the default display manager, if there is one, and is stopped along with
+// it.
+func AddTimeout(d time.Duration, fn TimerCallbackFn) (id int) {
+	if dm := GetDefaultDisplayManager(); dm != nil {
+		return dm.AddTimeout(d, fn)
+	}
+	return cdkTimeouts.AddTimeout(d, fn)
+}
+
+// CancelTimeout stops the timeout with the given id, as returned by
+// AddTimeout, whether added to the default display manager or before there
+// was one.
 func CancelTimeout(id int) bool {
+	if dm := GetDefaultDisplayManager(); dm != nil && dm.CancelTimeout(id) {
+		return true
+	}
 	return cdkTimeouts.Stop(id)
 }
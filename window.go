@@ -39,12 +39,51 @@ type Window interface {
 	ProcessEvent(evt Event) EventFlag
 }
 
+// KeyMapWindow is implemented by windows with key bindings of their own,
+// which apply while the window is the active window
+type KeyMapWindow interface {
+	Window
+
+	GetKeyMap() KeyMap
+	SetKeyMap(keyMap KeyMap)
+}
+
+// TypedWindow is implemented by windows that can be popup windows, those
+// that do not implement it are toplevel windows
+type TypedWindow interface {
+	Window
+
+	GetWindowType() WindowType
+	SetWindowType(windowType WindowType)
+}
+
+// windowKeyMap returns the key bindings of the window given, or nil if it
+// has none
+func windowKeyMap(w Window) KeyMap {
+	if kw, ok := w.(KeyMapWindow); ok {
+		return kw.GetKeyMap()
+	}
+	return nil
+}
+
+// windowTypeOf returns the type of the window given, WINDOW_TOPLEVEL unless
+// it is a TypedWindow
+func windowTypeOf(w Window) WindowType {
+	if tw, ok := w.(TypedWindow); ok {
+		return tw.GetWindowType()
+	}
+	return WINDOW_TOPLEVEL
+}
+
 // Basic window type
 type CWindow struct {
 	CObject
 
 	title   string
 	display DisplayManager
+	keyMap  KeyMap
+
+	windowType WindowType
 }
 
 func NewWindow(title string, d DisplayManager) Window {
@@ -61,12 +100,15 @@ func (w *CWindow) Init() bool {
 		return true
 	}
 	w.CObject.Init()
+	w.keyMap = NewKeyMap()
 	return false
 }
 
 func (w *CWindow) SetTitle(title string) {
 	if f := w.Emit(SignalSetTitle, w, title); f == EVENT_PASS {
 		w.title = title
+		// the display manager shows the title with the next draw
+		w.requestDraw()
 	}
 }
 
@@ -74,6 +116,42 @@ func (w *CWindow) GetTitle() string {
 	return w.title
 }
 
+// GetKeyMap returns the key bindings of the window, which apply while it is
+// the active window.
+func (w *CWindow) GetKeyMap() KeyMap {
+	return w.keyMap
+}
+
+func (w *CWindow) SetKeyMap(keyMap KeyMap) {
+	w.keyMap = keyMap
+}
+
+// GetWindowType returns whether the window is a toplevel or popup window.
+func (w *CWindow) GetWindowType() WindowType {
+	return w.windowType
+}
+
+// SetWindowType sets whether the window is a toplevel window, the default,
+// or a popup window.  Popup windows are stacked above toplevel windows and
+// are dismissed when the mouse button is pressed outside of them.
+func (w *CWindow) SetWindowType(windowType WindowType) {
+	w.windowType = windowType
+	w.requestDraw()
+}
+
+// requestDraw asks the display manager to draw the display again, without
+// waiting when it can, as windows are also changed while being drawn
+func (w *CWindow) requestDraw() {
+	if w.display == nil || !w.display.IsRunning() {
+		return
+	}
+	if dw, ok := w.display.(drawWaker); ok {
+		dw.wakeDraw()
+		return
+	}
+	w.display.RequestDraw()
+}
+
 func (w *CWindow) GetDisplayManager() DisplayManager {
 	return w.display
 }
@@ -0,0 +1,314 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+// SignalDismiss is emitted on a popup window when the mouse button is
+// pressed outside of it, with the popup window and display manager as argv.
+// The popup is removed from the display manager unless a listener returns
+// EVENT_STOP.
+const SignalDismiss Signal = "dismiss"
+
+// GetWindowStack returns the windows of the display manager in the order
+// they are composited, from the bottom to the top.  Popup windows are always
+// stacked above toplevel windows, and the active window above the others of
+// its type.
+func (d *CDisplayManager) GetWindowStack() []Window {
+	d.Lock()
+	defer d.Unlock()
+	return d.windowStack()
+}
+
+// RaiseWindow stacks the window given above the other windows of its type.
+func (d *CDisplayManager) RaiseWindow(w Window) {
+	d.restackWindow(w, true)
+}
+
+// LowerWindow stacks the window given below the other windows of its type.
+func (d *CDisplayManager) LowerWindow(w Window) {
+	d.restackWindow(w, false)
+}
+
+func (d *CDisplayManager) restackWindow(w Window, raise bool) {
+	d.Lock()
+	idx := -1
+	for i, sw := range d.wStack {
+		if sw.ObjectID() == w.ObjectID() {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		d.Unlock()
+		d.LogError("display does not have window: %v", w)
+		return
+	}
+	stack := append(append([]Window{}, d.wStack[:idx]...), d.wStack[idx+1:]...)
+	if raise {
+		d.wStack = append(stack, w)
+	} else {
+		d.wStack = append([]Window{w}, stack...)
+	}
+	d.Unlock()
+	d.requestWindowDraw()
+}
+
+// GetWindowRegion returns the region of the display the window given covers,
+// which is the whole display unless set with SetWindowRegion or MoveWindow.
+func (d *CDisplayManager) GetWindowRegion(w Window) Region {
+	d.Lock()
+	defer d.Unlock()
+	return d.windowRegion(d.windowIndex(w))
+}
+
+// SetWindowRegion sets the region of the display the window given covers,
+// and is drawn within.  A region without size makes the window cover the
+// whole display again, following its size.
+func (d *CDisplayManager) SetWindowRegion(w Window, region Region) {
+	d.Lock()
+	wid := d.windowIndex(w)
+	if wid < 0 {
+		d.Unlock()
+		d.LogError("display does not have window: %v", w)
+		return
+	}
+	if region.W <= 0 || region.H <= 0 {
+		d.wRegion[wid] = nil
+	} else {
+		d.wRegion[wid] = NewRegion(region.X, region.Y, region.W, region.H)
+	}
+	d.Unlock()
+	d.requestWindowDraw()
+}
+
+// MoveWindow moves the window given to the position given, keeping its size.
+func (d *CDisplayManager) MoveWindow(w Window, x, y int) {
+	region := d.GetWindowRegion(w)
+	region.X, region.Y = x, y
+	d.SetWindowRegion(w, region)
+}
+
+// RemoveWindow removes the window given from the display manager.  The
+// topmost window left becomes the active window if the window removed was.
+func (d *CDisplayManager) RemoveWindow(w Window) {
+	d.Lock()
+	wid := d.windowIndex(w)
+	if wid < 0 {
+		d.Unlock()
+		d.LogError("display does not have window: %v", w)
+		return
+	}
+	active := d.activeWindow()
+	d.windows = append(d.windows[:wid], d.windows[wid+1:]...)
+	d.wCanvas = append(d.wCanvas[:wid], d.wCanvas[wid+1:]...)
+	d.wRegion = append(d.wRegion[:wid], d.wRegion[wid+1:]...)
+	for i, sw := range d.wStack {
+		if sw.ObjectID() == w.ObjectID() {
+			d.wStack = append(d.wStack[:i], d.wStack[i+1:]...)
+			break
+		}
+	}
+	if active != nil && active.ObjectID() != w.ObjectID() {
+		d.active = d.windowIndex(active)
+	} else if stack := d.windowStack(); len(stack) > 0 {
+		d.active = d.windowIndex(stack[len(stack)-1])
+	} else {
+		d.active = -1
+	}
+	if d.rendered != nil && d.rendered.ObjectID() == w.ObjectID() {
+		d.rendered = nil
+	}
+	d.Unlock()
+	d.cursorLock.Lock()
+	delete(d.cursors, w.ObjectID())
+	if d.cursorOwner != nil && d.cursorOwner.ObjectID() == w.ObjectID() {
+		d.cursorOwner = nil
+	}
+	d.cursorLock.Unlock()
+	if owner := d.GetPointerGrab(); owner != nil && owner.ObjectID() == w.ObjectID() {
+		d.breakPointerGrab()
+	}
+	if entry, _ := d.dropModal(w); entry != nil {
+		d.closedModal(entry, nil)
+	}
+	d.requestWindowDraw()
+}
+
+func (d *CDisplayManager) requestWindowDraw() {
+	if d.IsRunning() {
+		d.RequestDraw()
+	}
+}
+
+// windowStack returns the windows from the bottom to the top, the toplevel
+// windows in the order they are stacked followed by the modal windows and
+// then the popup windows, with the active window above the others of its
+// type
+func (d *CDisplayManager) windowStack() (stack []Window) {
+	active := d.activeWindow()
+	var modals []Window
+	for _, m := range d.modalWindows() {
+		if d.windowIndex(m) > -1 {
+			modals = append(modals, m)
+		}
+	}
+	isModal := func(w Window) bool {
+		for _, m := range modals {
+			if m.ObjectID() == w.ObjectID() {
+				return true
+			}
+		}
+		return false
+	}
+	for _, popups := range []bool{false, true} {
+		var top Window
+		for _, w := range d.wStack {
+			if isModal(w) || (windowTypeOf(w) == WINDOW_POPUP) != popups {
+				continue
+			}
+			if active != nil && w.ObjectID() == active.ObjectID() {
+				top = w
+				continue
+			}
+			stack = append(stack, w)
+		}
+		if top != nil {
+			stack = append(stack, top)
+		}
+		if !popups {
+			stack = append(stack, modals...)
+		}
+	}
+	return
+}
+
+// windowRegion returns the region of the display covered by the window of
+// the index given
+func (d *CDisplayManager) windowRegion(wid int) Region {
+	if wid >= 0 && wid < len(d.wRegion) && d.wRegion[wid] != nil {
+		return *d.wRegion[wid]
+	}
+	w, h := 0, 0
+	if d.display != nil {
+		w, h = d.display.Size()
+	}
+	return MakeRegion(0, 0, w, h)
+}
+
+// layoutWindows sizes and positions the canvas of each window to the region
+// of the display it covers
+func (d *CDisplayManager) layoutWindows() {
+	style := d.GetTheme().Content.Normal
+	for wid, canvas := range d.wCanvas {
+		if canvas == nil {
+			continue
+		}
+		region := d.windowRegion(wid)
+		canvas.SetOrigin(region.Origin())
+		if size := canvas.GetSize(); size.W != region.W || size.H != region.H {
+			canvas.Resize(region.Size(), style)
+		}
+	}
+}
+
+// windowAt returns the topmost window covering the position given, or nil
+func (d *CDisplayManager) windowAt(x, y int) Window {
+	stack := d.windowStack()
+	for i := len(stack) - 1; i >= 0; i-- {
+		r := d.windowRegion(d.windowIndex(stack[i]))
+		if x >= r.X && x < r.X+r.W && y >= r.Y && y < r.Y+r.H {
+			return stack[i]
+		}
+	}
+	return nil
+}
+
+// windowMouseEvent returns the mouse event given with its position made
+// relative to the region of the window given
+func (d *CDisplayManager) windowMouseEvent(w Window, e *EventMouse) *EventMouse {
+	d.Lock()
+	r := d.windowRegion(d.windowIndex(w))
+	d.Unlock()
+	if r.X == 0 && r.Y == 0 {
+		return e
+	}
+	we := *e
+	we.x -= r.X
+	we.y -= r.Y
+	return &we
+}
+
+// dismissPopups emits SignalDismiss on the popup windows stacked above the
+// window pressed upon, removing those not kept by a listener, and returns
+// true if any were dismissed
+func (d *CDisplayManager) dismissPopups(pressed Window) (dismissed bool) {
+	d.Lock()
+	stack := d.windowStack()
+	d.Unlock()
+	for i := len(stack) - 1; i >= 0; i-- {
+		w := stack[i]
+		if pressed != nil && w.ObjectID() == pressed.ObjectID() {
+			break
+		}
+		if windowTypeOf(w) != WINDOW_POPUP {
+			break
+		}
+		if f := w.Emit(SignalDismiss, w, d); f == EVENT_PASS {
+			d.LogTrace("dismissing popup window: %v", w)
+			d.RemoveWindow(w)
+			dismissed = true
+		}
+	}
+	return
+}
+
+// processWindowMouse sends the mouse event given to the topmost window
+// beneath the mouse, falling back to the active window.  Pressing a button
+// outside of the popup windows dismisses them, consuming the press, and
+// pressing upon a window that is not active makes it the active window.
+// Mouse events for windows beneath the topmost modal window are swallowed.
+func (d *CDisplayManager) processWindowMouse(e *EventMouse) EventFlag {
+	d.Lock()
+	w := d.windowAt(e.x, e.y)
+	d.Unlock()
+	if e.State() == BUTTON_PRESS {
+		if d.dismissPopups(w) {
+			// the press consumed does not count as a click either
+			d.clickLock.Lock()
+			d.clickCount = 0
+			d.clickLock.Unlock()
+			return EVENT_STOP
+		}
+		if d.modalBlocks(w) {
+			modal := d.GetModal()
+			modal.Emit(SignalModalBlocked, modal, d, e)
+			return EVENT_STOP
+		}
+		if w != nil {
+			if active := d.ActiveWindow(); active == nil || active.ObjectID() != w.ObjectID() {
+				d.SetActiveWindow(w)
+			}
+		}
+	}
+	if d.modalBlocks(w) {
+		return EVENT_STOP
+	}
+	if w == nil {
+		w = d.ActiveWindow()
+	}
+	if w != nil {
+		return w.ProcessEvent(d.windowMouseEvent(w, e))
+	}
+	return EVENT_PASS
+}
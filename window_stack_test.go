@@ -0,0 +1,142 @@
+// Copyright 2021 The CDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use file except in compliance with the License.
+// You may obtain a copy of the license at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdk
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDisplayManagerWindowStack(t *testing.T) {
+	Convey("Stacking windows", t, func() {
+		d := NewDisplayManager("stack", OffscreenDisplayTtyPath)
+		defer d.Destroy()
+		d.CaptureDisplay(OffscreenDisplayTtyPath)
+		o := d.Display().(OffscreenDisplay)
+		o.SetSize(6, 3)
+
+		var seen []string
+		newWindow := func(name string, r rune, wt WindowType) Window {
+			w := NewWindow(name, d)
+			w.(TypedWindow).SetWindowType(wt)
+			w.Connect(SignalDraw, "stack-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+				canvas := argv[1].(Canvas)
+				for x := 0; x < canvas.Width(); x++ {
+					for y := 0; y < canvas.Height(); y++ {
+						_ = canvas.SetRune(x, y, r, StyleDefault)
+					}
+				}
+				return EVENT_STOP
+			})
+			w.Connect(SignalEvent, "stack-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+				if e, ok := argv[1].(*EventMouse); ok {
+					x, y := e.Position()
+					seen = append(seen, name+":"+e.State().String()+fmt.Sprintf(":%d,%d", x, y))
+				}
+				return EVENT_PASS
+			})
+			return w
+		}
+		screen := func() (lines []string) {
+			d.DrawScreen()
+			o.Show()
+			cells, w, h := o.GetContents()
+			for y := 0; y < h; y++ {
+				line := ""
+				for x := 0; x < w; x++ {
+					line += string(cells[y*w+x].Runes[0])
+				}
+				lines = append(lines, line)
+			}
+			return
+		}
+		names := func(windows []Window) (list []string) {
+			for _, w := range windows {
+				list = append(list, w.GetTitle())
+			}
+			return
+		}
+
+		a := newWindow("a", 'a', WINDOW_TOPLEVEL)
+		b := newWindow("b", 'b', WINDOW_TOPLEVEL)
+		p := newWindow("p", 'p', WINDOW_POPUP)
+		d.SetActiveWindow(a)
+		d.AddWindow(p)
+		d.AddWindow(b)
+		d.SetWindowRegion(b, MakeRegion(1, 1, 2, 2))
+		d.SetWindowRegion(p, MakeRegion(3, 0, 2, 2))
+		So(d.GetWindowRegion(a), ShouldResemble, MakeRegion(0, 0, 6, 3))
+
+		Convey("composites the windows from the bottom up", func() {
+			So(names(d.GetWindowStack()), ShouldResemble, []string{"b", "a", "p"})
+			So(screen(), ShouldResemble, []string{"aaappa", "aaappa", "aaaaaa"})
+			d.SetActiveWindow(b)
+			So(names(d.GetWindowStack()), ShouldResemble, []string{"a", "b", "p"})
+			So(screen(), ShouldResemble, []string{"aaappa", "abbppa", "abbaaa"})
+			d.MoveWindow(b, 3, 1)
+			So(screen(), ShouldResemble, []string{"aaappa", "aaappa", "aaabba"})
+			d.RaiseWindow(p)
+			d.LowerWindow(p)
+			So(names(d.GetWindowStack()), ShouldResemble, []string{"a", "b", "p"})
+			d.SetWindowRegion(b, Region{})
+			So(d.GetWindowRegion(b), ShouldResemble, MakeRegion(0, 0, 6, 3))
+		})
+
+		Convey("sends mouse events to the window beneath", func() {
+			var prev *EventMouse
+			mouse := func(x, y int, btn ButtonMask) {
+				prev = newEventMouse(prev, x, y, btn, ModNone)
+				d.ProcessEvent(prev)
+			}
+			d.SetActiveWindow(b)
+			mouse(4, 1, ButtonNone)
+			mouse(2, 2, ButtonNone)
+			mouse(0, 0, ButtonNone)
+			So(seen, ShouldResemble, []string{"p:MOUSE_MOVE:1,1", "b:MOUSE_MOVE:1,1", "a:MOUSE_MOVE:0,0"})
+			So(d.ActiveWindow(), ShouldEqual, b)
+
+			Convey("and dismisses popups when pressed outside of them", func() {
+				seen = nil
+				keep := true
+				p.Connect(SignalDismiss, "stack-test", func(_ []interface{}, argv ...interface{}) EventFlag {
+					if keep {
+						return EVENT_STOP
+					}
+					return EVENT_PASS
+				})
+				mouse(0, 0, Button1)
+				mouse(0, 0, ButtonNone)
+				So(seen, ShouldResemble, []string{"a:BUTTON_PRESS:0,0", "a:BUTTON_RELEASE:0,0", "a:MOUSE_CLICK:0,0"})
+				So(d.ActiveWindow(), ShouldEqual, a)
+				So(names(d.GetWindowStack()), ShouldResemble, []string{"b", "a", "p"})
+				seen = nil
+				keep = false
+				mouse(0, 0, Button1)
+				mouse(0, 0, ButtonNone)
+				So(names(d.GetWindowStack()), ShouldResemble, []string{"b", "a"})
+				So(seen, ShouldResemble, []string{"a:BUTTON_RELEASE:0,0"})
+			})
+		})
+
+		Convey("removes windows", func() {
+			d.SetActiveWindow(p)
+			d.RemoveWindow(p)
+			So(names(d.GetWindows()), ShouldResemble, []string{"a", "b"})
+			So(d.ActiveWindow(), ShouldEqual, b)
+		})
+	})
+}